@@ -1,75 +1,111 @@
 package nomi
 
 import (
-	"bytes"
-	"encoding/json"
-	"github.com/google/uuid"
-	"io"
+	"context"
 	"net/http"
-	"net/url"
+	"time"
 )
 
 type API interface {
 	// GetNomis allows you to list all the Nomis associated with your account
 	GetNomis() (GetNomisResponse, error)
+	// GetNomisContext is like GetNomis but uses ctx for cancellation and deadlines
+	GetNomisContext(ctx context.Context) (GetNomisResponse, error)
 	// GetNomi allows you to get the details of a specific Nomi associated with your account
 	GetNomi(nomiID string) (GetNomiResponse, error)
+	// GetNomiContext is like GetNomi but uses ctx for cancellation and deadlines
+	GetNomiContext(ctx context.Context, nomiID string) (GetNomiResponse, error)
 	// SendMessage allows you to send a message in the main chat for this Nomi and get a reply
 	SendMessage(nomiID string, body SendMessageBody) (SendMessageResponse, error)
+	// SendMessageContext is like SendMessage but uses ctx for cancellation and deadlines
+	SendMessageContext(ctx context.Context, nomiID string, body SendMessageBody) (SendMessageResponse, error)
 	// GetRooms allows you to list all the Rooms associated with your account
 	GetRooms() (GetRoomsResponse, error)
+	// GetRoomsContext is like GetRooms but uses ctx for cancellation and deadlines
+	GetRoomsContext(ctx context.Context) (GetRoomsResponse, error)
 	// CreateRoom allows you to create a new Room associated with your account
 	CreateRoom(body CreateRoomBody) (CreateRoomResponse, error)
+	// CreateRoomContext is like CreateRoom but uses ctx for cancellation and deadlines
+	CreateRoomContext(ctx context.Context, body CreateRoomBody) (CreateRoomResponse, error)
 	// GetRoom allows you to get the details of a specific Room associated with your account
 	GetRoom(roomID string) (GetRoomResponse, error)
+	// GetRoomContext is like GetRoom but uses ctx for cancellation and deadlines
+	GetRoomContext(ctx context.Context, roomID string) (GetRoomResponse, error)
 	// SendRoomMessage allows you to send a message in this Room. This method will not return a response to your message, if you want to get a response from your nomi, see RequestNomiRoomMessage
 	SendRoomMessage(roomID string, body SendRoomMessageBody) (SendRoomMessageResponse, error)
+	// SendRoomMessageContext is like SendRoomMessage but uses ctx for cancellation and deadlines
+	SendRoomMessageContext(ctx context.Context, roomID string, body SendRoomMessageBody) (SendRoomMessageResponse, error)
 	// RequestNomiRoomMessage allows you to make a Nomi send a message in a Room
 	RequestNomiRoomMessage(roomID string, body RequestNomiRoomMessageBody) (RequestNomiMessageResponse, error)
+	// RequestNomiRoomMessageContext is like RequestNomiRoomMessage but uses ctx for cancellation and deadlines
+	RequestNomiRoomMessageContext(ctx context.Context, roomID string, body RequestNomiRoomMessageBody) (RequestNomiMessageResponse, error)
 	// UpdateRoom allows you to edit the details of a Room
 	UpdateRoom(roomID string, body UpdateRoomBody) (UpdateRoomResponse, error)
+	// UpdateRoomContext is like UpdateRoom but uses ctx for cancellation and deadlines
+	UpdateRoomContext(ctx context.Context, roomID string, body UpdateRoomBody) (UpdateRoomResponse, error)
 	// DeleteRoom allows you to delete a Room associated with your account
 	DeleteRoom(roomID string) (success bool, err error)
+	// DeleteRoomContext is like DeleteRoom but uses ctx for cancellation and deadlines
+	DeleteRoomContext(ctx context.Context, roomID string) (success bool, err error)
 }
 
 type api struct {
-	apiKey  string
-	baseUrl string
+	apiKey     string
+	baseUrl    string
+	httpClient *http.Client
+	userAgent  string
+	headers    http.Header
+	timeout    time.Duration
+	retry      *RetryPolicy
+	limiter    *tokenBucket
+	quota      *quotaTracker
+	waitReady  *waitReady
+	// interceptors hook into every call, see WithInterceptors
+	interceptors []Interceptor
+	// logger logs every request when set, see WithLogger
+	logger *requestLogger
+	// maxMessageLength is checked before sending messages when it is above zero
+	maxMessageLength int
 }
 
-func NewClient(apiKey string) API {
-	return api{
-		apiKey:  apiKey,
-		baseUrl: "https://api.nomi.ai/v1/",
+// NewClient creates a client for the Nomi API authenticated with apiKey. By default, it talks to DefaultBaseURL
+// through http.DefaultClient; use the options to change that
+func NewClient(apiKey string, opts ...Option) API {
+	a := api{
+		apiKey:     apiKey,
+		baseUrl:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+		userAgent:  DefaultUserAgent,
+		headers:    http.Header{},
+		quota:      &quotaTracker{},
 	}
-}
 
-func (a api) GetNomis() (GetNomisResponse, error) {
-	var res GetNomisResponse
-
-	u, err := url.JoinPath(a.baseUrl, "nomis")
-	if err != nil {
-		return GetNomisResponse{}, err
+	for _, opt := range opts {
+		opt(&a)
 	}
 
-	req, err := http.NewRequest(http.MethodGet, u, nil)
-	if err != nil {
-		return GetNomisResponse{}, err
+	if a.timeout > 0 {
+		c := *a.httpClient
+		c.Timeout = a.timeout
+		a.httpClient = &c
 	}
-	req.Header.Add("Authorization", a.apiKey)
 
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return GetNomisResponse{}, err
-	}
-	defer response.Body.Close()
+	return a
+}
 
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return GetNomisResponse{}, err
-	}
+func (a api) GetNomis() (GetNomisResponse, error) {
+	return a.GetNomisContext(context.Background())
+}
 
-	err = json.Unmarshal(b, &res)
+func (a api) GetNomisContext(ctx context.Context) (GetNomisResponse, error) {
+	var res GetNomisResponse
+
+	err := a.do(ctx, operation{
+		name:   "GetNomis",
+		method: http.MethodGet,
+		path:   []string{"nomis"},
+		out:    &res,
+	})
 	if err != nil {
 		return GetNomisResponse{}, err
 	}
@@ -78,43 +114,20 @@ func (a api) GetNomis() (GetNomisResponse, error) {
 }
 
 func (a api) GetNomi(nomiID string) (GetNomiResponse, error) {
-	var res GetNomiResponse
-
-	id, err := uuid.Parse(nomiID)
-	if err != nil {
-		return GetNomiResponse{}, InvalidRouteParams
-	}
-
-	u, err := url.JoinPath(a.baseUrl, "nomis", id.String())
-	if err != nil {
-		return GetNomiResponse{}, err
-	}
-
-	req, err := http.NewRequest(http.MethodGet, u, nil)
-	if err != nil {
-		return GetNomiResponse{}, err
-	}
-	req.Header.Add("Authorization", a.apiKey)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return GetNomiResponse{}, err
-	}
-	defer response.Body.Close()
-
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return GetNomiResponse{}, err
-	}
+	return a.GetNomiContext(context.Background(), nomiID)
+}
 
-	if response.StatusCode < 200 || response.StatusCode > 299 {
-		err = parseError(b)
-		if err != nil {
-			return GetNomiResponse{}, err
-		}
-	}
+func (a api) GetNomiContext(ctx context.Context, nomiID string) (GetNomiResponse, error) {
+	var res GetNomiResponse
 
-	err = json.Unmarshal(b, &res)
+	id, invalid := parseID(nomiID)
+	err := a.do(ctx, operation{
+		name:    "GetNomi",
+		method:  http.MethodGet,
+		path:    []string{"nomis", id},
+		out:     &res,
+		invalid: invalid,
+	})
 	if err != nil {
 		return GetNomiResponse{}, err
 	}
@@ -123,49 +136,27 @@ func (a api) GetNomi(nomiID string) (GetNomiResponse, error) {
 }
 
 func (a api) SendMessage(nomiID string, body SendMessageBody) (SendMessageResponse, error) {
-	var res SendMessageResponse
-
-	id, err := uuid.Parse(nomiID)
-	if err != nil {
-		return SendMessageResponse{}, err
-	}
-
-	u, err := url.JoinPath(a.baseUrl, "nomis", id.String(), "chat")
-	if err != nil {
-		return SendMessageResponse{}, err
-	}
-
-	reqBody, err := json.Marshal(body)
-	if err != nil {
-		return SendMessageResponse{}, err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, u, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return SendMessageResponse{}, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", a.apiKey)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return SendMessageResponse{}, err
-	}
-	defer response.Body.Close()
-
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return SendMessageResponse{}, err
-	}
+	return a.SendMessageContext(context.Background(), nomiID, body)
+}
 
-	if response.StatusCode < 200 || response.StatusCode > 299 {
-		err = parseError(b)
-		if err != nil {
-			return SendMessageResponse{}, err
-		}
-	}
+func (a api) SendMessageContext(ctx context.Context, nomiID string, body SendMessageBody) (SendMessageResponse, error) {
+	var res SendMessageResponse
 
-	err = json.Unmarshal(b, &res)
+	id, invalid := parseID(nomiID)
+	if invalid == nil {
+		invalid = body.Validate(a.maxMessageLength)
+	}
+
+	err := a.do(ctx, operation{
+		name:       "SendMessage",
+		method:     http.MethodPost,
+		path:       []string{"nomis", id, "chat"},
+		body:       body,
+		message:    true,
+		waitsReady: true,
+		out:        &res,
+		invalid:    invalid,
+	})
 	if err != nil {
 		return SendMessageResponse{}, err
 	}
@@ -0,0 +1,330 @@
+package nomi
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a response stored by a CacheBackend, encoded as JSON
+type CacheEntry struct {
+	Value  []byte    `json:"value"`
+	Stored time.Time `json:"stored"`
+}
+
+// CacheBackend stores the responses cached by WithCache. Entries are kept past the TTL, to be served when the API
+// fails, so backends should evict them on their own terms, e.g. by capacity. Implementations must be safe for
+// concurrent use
+type CacheBackend interface {
+	Get(ctx context.Context, key string) (CacheEntry, bool, error)
+	Set(ctx context.Context, key string, entry CacheEntry) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// CacheOptions configures WithCache
+type CacheOptions struct {
+	// TTL is how long a response is served without calling the API. Defaults to DefaultCacheTTL
+	TTL time.Duration
+	// MaxStale is how long after the TTL a response is still served when the API fails with a transient error.
+	// Defaults to DefaultCacheMaxStale, and a negative value never serves stale responses
+	MaxStale time.Duration
+	// Backend stores the responses. Defaults to an LRUCache of DefaultCacheCapacity entries
+	Backend CacheBackend
+	// OnBackendError is called with the errors of the backend, which are otherwise ignored
+	OnBackendError func(error)
+	// Namespace prefixes the keys of the entries, so that clients of different accounts or servers can share a
+	// backend. Defaults to a hash of the API key and the base URL of a client created by NewClient. Set it when
+	// sharing a backend between clients wrapped in other decorators, which hide them
+	Namespace string
+}
+
+const (
+	DefaultCacheTTL      = 30 * time.Second
+	DefaultCacheMaxStale = 5 * time.Minute
+	DefaultCacheCapacity = 1000
+)
+
+// WithCache wraps client to cache the responses of GetNomis, GetNomi, GetRooms and GetRoom. The Rooms are dropped from
+// the cache when CreateRoom, UpdateRoom or DeleteRoom succeed. When the API fails with a transient error, as told by
+// IsTransient, the last response is served instead, within MaxStale. Other errors, such as Unauthorized or NotFound,
+// are always returned. Keep in mind that Room.Status changes on its own: do not wait for a Room with a caching client
+func WithCache(client API, opts CacheOptions) API {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultCacheTTL
+	}
+	if opts.MaxStale == 0 {
+		opts.MaxStale = DefaultCacheMaxStale
+	}
+	if opts.Backend == nil {
+		opts.Backend = NewLRUCache(DefaultCacheCapacity)
+	}
+	if c, ok := client.(api); opts.Namespace == "" && ok {
+		opts.Namespace = c.namespace()
+	}
+
+	return &cachingAPI{API: client, opts: opts}
+}
+
+type cachingAPI struct {
+	API
+	opts CacheOptions
+}
+
+// namespace identifies the account and the server of the client
+func (a api) namespace() string {
+	sum := sha256.Sum256([]byte(a.baseUrl + "\n" + a.apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// key returns the key of the entry name in the namespace of the cache
+func (c *cachingAPI) key(name string) string {
+	if c.opts.Namespace == "" {
+		return name
+	}
+
+	return c.opts.Namespace + ":" + name
+}
+
+func (c *cachingAPI) backendError(err error) {
+	if err != nil && c.opts.OnBackendError != nil {
+		c.opts.OnBackendError(err)
+	}
+}
+
+// servesStale reports whether a stale response may replace the error of the API
+func servesStale(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && IsTransient(err)
+}
+
+// cachedCall returns the response cached under key if it is fresh, otherwise calls fetch and caches its response
+func cachedCall[T any](ctx context.Context, c *cachingAPI, key string, fetch func() (T, error)) (T, error) {
+	key = c.key(key)
+	entry, found, err := c.opts.Backend.Get(ctx, key)
+	c.backendError(err)
+
+	var cached T
+	if found && json.Unmarshal(entry.Value, &cached) != nil {
+		found = false
+	}
+	age := time.Since(entry.Stored)
+	if found && age < c.opts.TTL {
+		return cached, nil
+	}
+
+	res, err := fetch()
+	if err != nil {
+		stale := c.opts.MaxStale > 0 && age < c.opts.TTL+c.opts.MaxStale
+		if found && stale && servesStale(ctx, err) {
+			return cached, nil
+		}
+		if found && (errors.Is(err, NotFound) || errors.Is(err, RoomNotFound)) {
+			c.backendError(c.opts.Backend.Delete(ctx, key))
+		}
+		return res, err
+	}
+
+	value, err := json.Marshal(res)
+	if err == nil {
+		err = c.opts.Backend.Set(ctx, key, CacheEntry{Value: value, Stored: time.Now()})
+	}
+	c.backendError(err)
+
+	return res, nil
+}
+
+// cacheKey returns the key of a response about the Nomi or Room id, or false if id is invalid
+func cacheKey(prefix string, id string) (string, bool) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return "", false
+	}
+
+	return prefix + parsed.String(), true
+}
+
+func (c *cachingAPI) invalidateRooms(ctx context.Context, roomID string) {
+	keys := []string{c.key("rooms")}
+	if key, ok := cacheKey("room:", roomID); ok {
+		keys = append(keys, c.key(key))
+	}
+
+	c.backendError(c.opts.Backend.Delete(ctx, keys...))
+}
+
+func (c *cachingAPI) Quota() QuotaStats {
+	stats, _ := QuotaOf(c.API)
+	return stats
+}
+
+func (c *cachingAPI) quotaOf() (QuotaStats, bool) {
+	return QuotaOf(c.API)
+}
+
+func (c *cachingAPI) GetNomis() (GetNomisResponse, error) {
+	return c.GetNomisContext(context.Background())
+}
+
+func (c *cachingAPI) GetNomisContext(ctx context.Context) (GetNomisResponse, error) {
+	return cachedCall(ctx, c, "nomis", func() (GetNomisResponse, error) {
+		return c.API.GetNomisContext(ctx)
+	})
+}
+
+func (c *cachingAPI) GetNomi(nomiID string) (GetNomiResponse, error) {
+	return c.GetNomiContext(context.Background(), nomiID)
+}
+
+func (c *cachingAPI) GetNomiContext(ctx context.Context, nomiID string) (GetNomiResponse, error) {
+	key, ok := cacheKey("nomi:", nomiID)
+	if !ok {
+		return c.API.GetNomiContext(ctx, nomiID)
+	}
+
+	return cachedCall(ctx, c, key, func() (GetNomiResponse, error) {
+		return c.API.GetNomiContext(ctx, nomiID)
+	})
+}
+
+func (c *cachingAPI) GetRooms() (GetRoomsResponse, error) {
+	return c.GetRoomsContext(context.Background())
+}
+
+func (c *cachingAPI) GetRoomsContext(ctx context.Context) (GetRoomsResponse, error) {
+	return cachedCall(ctx, c, "rooms", func() (GetRoomsResponse, error) {
+		return c.API.GetRoomsContext(ctx)
+	})
+}
+
+func (c *cachingAPI) GetRoom(roomID string) (GetRoomResponse, error) {
+	return c.GetRoomContext(context.Background(), roomID)
+}
+
+func (c *cachingAPI) GetRoomContext(ctx context.Context, roomID string) (GetRoomResponse, error) {
+	key, ok := cacheKey("room:", roomID)
+	if !ok {
+		return c.API.GetRoomContext(ctx, roomID)
+	}
+
+	return cachedCall(ctx, c, key, func() (GetRoomResponse, error) {
+		return c.API.GetRoomContext(ctx, roomID)
+	})
+}
+
+func (c *cachingAPI) CreateRoom(body CreateRoomBody) (CreateRoomResponse, error) {
+	return c.CreateRoomContext(context.Background(), body)
+}
+
+func (c *cachingAPI) CreateRoomContext(ctx context.Context, body CreateRoomBody) (CreateRoomResponse, error) {
+	res, err := c.API.CreateRoomContext(ctx, body)
+	if err == nil {
+		c.invalidateRooms(ctx, res.UUID.String())
+	}
+
+	return res, err
+}
+
+func (c *cachingAPI) UpdateRoom(roomID string, body UpdateRoomBody) (UpdateRoomResponse, error) {
+	return c.UpdateRoomContext(context.Background(), roomID, body)
+}
+
+func (c *cachingAPI) UpdateRoomContext(ctx context.Context, roomID string, body UpdateRoomBody) (UpdateRoomResponse, error) {
+	res, err := c.API.UpdateRoomContext(ctx, roomID, body)
+	if err == nil {
+		c.invalidateRooms(ctx, roomID)
+	}
+
+	return res, err
+}
+
+func (c *cachingAPI) DeleteRoom(roomID string) (bool, error) {
+	return c.DeleteRoomContext(context.Background(), roomID)
+}
+
+func (c *cachingAPI) DeleteRoomContext(ctx context.Context, roomID string) (bool, error) {
+	success, err := c.API.DeleteRoomContext(ctx, roomID)
+	if err == nil {
+		c.invalidateRooms(ctx, roomID)
+	}
+
+	return success, err
+}
+
+// LRUCache is an in-memory CacheBackend keeping the most recently used entries
+type LRUCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewLRUCache creates an LRUCache holding up to capacity entries
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{capacity: max(capacity, 1), order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (l *LRUCache) Get(ctx context.Context, key string) (CacheEntry, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return CacheEntry{}, false, nil
+	}
+	l.order.MoveToFront(element)
+
+	return element.Value.(*lruItem).entry, true, nil
+}
+
+func (l *LRUCache) Set(ctx context.Context, key string, entry CacheEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.entries[key]; ok {
+		element.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(element)
+		return nil
+	}
+
+	l.entries[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+	if l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruItem).key)
+	}
+
+	return nil
+}
+
+func (l *LRUCache) Delete(ctx context.Context, keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := l.entries[key]; ok {
+			l.order.Remove(element)
+			delete(l.entries, key)
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of entries in the cache
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
@@ -0,0 +1,315 @@
+// Package cassette records the HTTP requests made by the SDK and their responses to JSON files, and replays them
+// later without network access. This makes tests written against the real Nomi API deterministic:
+//
+//	recorder, err := cassette.New("testdata/chat.json", cassette.Replay)
+//	if err != nil {
+//		return err
+//	}
+//	client := nomi.NewClient(apiKey, nomi.WithHTTPClient(recorder.Client()))
+//
+// In Record mode, the requests go through to the API and the cassette is written by Save. The Authorization header
+// is never recorded. In Replay mode, requests are matched by method, path and body against the recorded ones, each
+// recorded interaction being used once in order, and unmatched requests fail with an *UnmatchedRequestError.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Mode is what a Transport does with requests
+type Mode int
+
+const (
+	// Replay answers requests with the recorded responses, without any network access
+	Replay Mode = iota
+	// Record sends requests to the real server and records them with their responses
+	Record
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Replay:
+		return "replay"
+	case Record:
+		return "record"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// ScrubbedHeaders are the request headers that are never recorded
+var ScrubbedHeaders = []string{"Authorization"}
+
+// Request is a recorded request
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded response
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is a request and the response it got
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is the content of a cassette file
+type Cassette struct {
+	// Meta holds values needed to replay the cassette, e.g. the IDs the recorded requests refer to
+	Meta         map[string]string `json:"meta,omitempty"`
+	Interactions []Interaction     `json:"interactions"`
+}
+
+// UnmatchedRequestError is returned in Replay mode for requests that match no unused recorded interaction
+type UnmatchedRequestError struct {
+	Path    string
+	Request Request
+}
+
+func (e *UnmatchedRequestError) Error() string {
+	return fmt.Sprintf("cassette %s: no recorded interaction left for %s %s with body %q", e.Path, e.Request.Method, e.Request.Path, e.Request.Body)
+}
+
+// Transport is an http.RoundTripper recording or replaying interactions
+type Transport struct {
+	// Real sends the requests in Record mode. Defaults to http.DefaultTransport
+	Real http.RoundTripper
+
+	path string
+	mode Mode
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New creates a Transport for the cassette file at path. In Replay mode, the file must exist
+func New(path string, mode Mode) (*Transport, error) {
+	t := &Transport{path: path, mode: mode}
+
+	if mode == Replay {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(b, &t.cassette)
+		if err != nil {
+			return nil, fmt.Errorf("cassette %s: %w", path, err)
+		}
+		t.used = make([]bool, len(t.cassette.Interactions))
+	}
+
+	return t, nil
+}
+
+// Mode returns whether the transport records or replays
+func (t *Transport) Mode() Mode {
+	return t.mode
+}
+
+// Client returns an http.Client using the transport, to give to nomi.WithHTTPClient
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.mode == Replay {
+		return t.replay(req, recorded)
+	}
+
+	return t.record(req, recorded)
+}
+
+func (t *Transport) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, interaction := range t.cassette.Interactions {
+		if t.used[i] || !matches(interaction.Request, recorded) {
+			continue
+		}
+		t.used[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, &UnmatchedRequestError{Path: t.path, Request: recorded}
+}
+
+func (t *Transport) record(req *http.Request, recorded Request) (*http.Response, error) {
+	next := t.Real
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	res, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// recorded interactions count as used, they are not left over for a replay
+	t.used = append(t.used, true)
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     res.Header.Clone(),
+			Body:       string(body),
+		},
+	})
+
+	return res, nil
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing in Replay mode
+func (t *Transport) Save() error {
+	if t.mode != Record {
+		return nil
+	}
+
+	t.mu.Lock()
+	b, err := json.MarshalIndent(t.cassette, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(t.path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(t.path, append(b, '\n'), 0o644)
+}
+
+// Meta returns the value stored with the cassette under key, or "" if there is none
+func (t *Transport) Meta(key string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.cassette.Meta[key]
+}
+
+// SetMeta stores value with the cassette under key, to be read back with Meta when replaying
+func (t *Transport) SetMeta(key string, value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cassette.Meta == nil {
+		t.cassette.Meta = map[string]string{}
+	}
+	t.cassette.Meta[key] = value
+}
+
+// Unused returns the recorded interactions that were not replayed yet. It returns nil in Record mode
+func (t *Transport) Unused() []Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var unused []Interaction
+	for i, interaction := range t.cassette.Interactions {
+		if !t.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+
+	return unused
+}
+
+// recordRequest copies what is recorded of req, leaving its body readable
+func recordRequest(req *http.Request) (Request, error) {
+	recorded := Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+		Header: req.Header.Clone(),
+	}
+	for _, header := range ScrubbedHeaders {
+		recorded.Header.Del(header)
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return Request{}, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		recorded.Body = string(body)
+	}
+
+	return recorded, nil
+}
+
+// matches reports whether a request is the same as a recorded one. JSON bodies are compared by value, whatever the
+// order of their keys and their whitespace
+func matches(recorded Request, req Request) bool {
+	return recorded.Method == req.Method &&
+		recorded.Path == req.Path &&
+		recorded.Query == req.Query &&
+		sameBody(recorded.Body, req.Body)
+}
+
+func sameBody(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	var valueA, valueB any
+	if json.Unmarshal([]byte(a), &valueA) != nil || json.Unmarshal([]byte(b), &valueB) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(valueA, valueB)
+}
+
+// IsUnmatched reports whether err comes from a request missing from the cassette
+func IsUnmatched(err error) bool {
+	var unmatched *UnmatchedRequestError
+	return errors.As(err, &unmatched)
+}
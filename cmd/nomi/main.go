@@ -0,0 +1,11 @@
+// Command nomi is a command-line client for the Nomi API. Run it without arguments for the list of commands
+package main
+
+import (
+	"github.com/vhalmd/nomi-go-sdk/internal/cli"
+	"os"
+)
+
+func main() {
+	os.Exit(cli.New().Run(os.Args[1:]))
+}
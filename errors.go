@@ -1,9 +1,13 @@
 package nomi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"net/http"
+	"slices"
 )
 
 var NotFound = errors.New("the specified nomi was not found. it may not exist or may not be associated with this account")
@@ -27,21 +31,22 @@ var RoomNomiNotFound = errors.New("the specified Nomi is not found within the sp
 var RoomStillCreating = errors.New("immediately after the creation of a room, there is a short period of several seconds before any messages can be sent to the room")
 var RoomNomiNotReadyForMessage = errors.New("the Nomi is already replying a user message and so cannot reply to this message")
 
-// InvalidBody TODO: create an Error type, maybe?
+// InvalidBody means there is an issue with the request body. The details are available in Error.Issues
 var InvalidBody = errors.New("issue will be detailed in the errors.issues key, but there is an issue with the request body. this can happen if the messageText key is missing, the wrong type, or an empty string")
 
 type APIErrorIssues struct {
-	Code       string   `json:"code"`
-	Expected   string   `json:"expected"`
-	Received   string   `json:"received"`
-	Path       []string `json:"path"`
-	Message    string   `json:"message"`
-	Validation string   `json:"validation"`
+	Code     string `json:"code"`
+	Expected string `json:"expected"`
+	Received string `json:"received"`
+	// Path holds the keys leading to the invalid value, which are strings for objects and numbers for arrays
+	Path       []any  `json:"path"`
+	Message    string `json:"message"`
+	Validation string `json:"validation"`
 }
 
 type APIError struct {
-	Type   string         `json:"type"`
-	Issues APIErrorIssues `json:"issues"`
+	Type   string           `json:"type"`
+	Issues []APIErrorIssues `json:"issues"`
 }
 type APIErrorResponse struct {
 	Err APIError `json:"error"`
@@ -51,52 +56,150 @@ func (a APIErrorResponse) Error() string {
 	return fmt.Sprintf("Err: %+v", a.Err)
 }
 
-func parseError(b []byte) error {
+// Error is returned when the Nomi API answers with a non 2xx status code. It keeps everything the server
+// sent back and unwraps to the sentinel error matching its Type, so errors.Is(err, nomi.NotFound) still works
+type Error struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Type is the error.type key of the response body, e.g. "NomiNotFound". It is empty when the body could not be parsed
+	Type string
+	// Issues details what is wrong with the request, mostly for InvalidBody errors
+	Issues []APIErrorIssues
+	// Method and URL identify the request that failed
+	Method string
+	URL    string
+	// RequestID is the value of the X-Request-Id response header, if any
+	RequestID string
+	// Header holds the response headers
+	Header http.Header
+	// Body is the raw response body
+	Body []byte
+
+	// sentinel is the error matching Type, nil when the type is unknown
+	sentinel error
+}
+
+func (e *Error) Error() string {
+	var msg string
+	switch {
+	case e.sentinel != nil:
+		msg = fmt.Sprintf("%s: %s", e.Type, e.sentinel)
+	case e.Type != "":
+		msg = fmt.Sprintf("unknown error type %s", e.Type)
+	default:
+		msg = fmt.Sprintf("unexpected response: %s", e.Body)
+	}
+
+	for _, issue := range e.Issues {
+		msg += fmt.Sprintf("; %v: %s", issue.Path, issue.Message)
+	}
+
+	return fmt.Sprintf("%s %s returned status %d: %s", e.Method, e.URL, e.StatusCode, msg)
+}
+
+// Unwrap returns the sentinel error matching the error type, e.g. NotFound for "NomiNotFound"
+func (e *Error) Unwrap() error {
+	return e.sentinel
+}
+
+// Is reports whether target is an *Error with the same Type, so errors.Is(err, &nomi.Error{Type: "NoReply"}) also works
+// for error types this package does not know about
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return t.Type != "" && t.Type == e.Type
+}
+
+// parseError builds the *Error for a failed response with its already read body
+func parseError(response *http.Response, b []byte) error {
 	var apiErr APIErrorResponse
 
-	err := json.Unmarshal(b, &apiErr)
-	if err != nil {
-		return err
+	// the error type is still decoded when the other fields don't have the expected shape,
+	// so only a body without a type at all is treated as unparseable
+	_ = json.Unmarshal(b, &apiErr)
+
+	e := &Error{
+		StatusCode: response.StatusCode,
+		Type:       apiErr.Err.Type,
+		Issues:     apiErr.Err.Issues,
+		RequestID:  response.Header.Get("X-Request-Id"),
+		Header:     response.Header,
+		Body:       b,
+		sentinel:   errorTypes[apiErr.Err.Type],
+	}
+	if response.Request != nil {
+		e.Method = response.Request.Method
+		e.URL = response.Request.URL.String()
 	}
 
-	switch apiErr.Err.Type {
-	case "NomiNotFound":
-		return NotFound
-	case "InvalidRouteParams":
-		return InvalidRouteParams
-	case "InvalidContentType":
-		return InvalidContentType
-	case "NoReply":
-		return NoReply
-	case "NomiStillResponding":
-		return StillResponding
-	case "NomiNotReady":
-		return NotReady
-	case "OngoingVoiceCallDetected":
-		return OngoingVoiceCallDetected
-	case "MessageLengthLimitExceeded":
-		return MessageLengthLimitExceeded
-	case "LimitExceeded":
-		return LimitExceeded
-	case "InvalidBody":
-		return InvalidBody
-	case "InsufficientPlan":
-		return InsufficientPlan
-	case "ExceededRoomLimit":
-		return ExceededRoomLimit
-	case "RoomNomiCountTooSmall":
-		return RoomNomiCountTooSmall
-	case "RoomNomiCountTooLarge":
-		return RoomNomiCountTooLarge
-	case "RoomNotFound":
-		return RoomNotFound
-	case "RoomNomiNotFound":
-		return RoomNomiNotFound
-	case "RoomStillCreating":
-		return RoomStillCreating
-	case "RoomNomiNotReadyForMessage":
-		return RoomNomiNotReadyForMessage
-	default:
-		return fmt.Errorf("unknown error: %w", err)
+	return e
+}
+
+// errorTypes maps the error types sent by the Nomi API to the errors declared above
+var errorTypes = map[string]error{
+	"NomiNotFound":               NotFound,
+	"InvalidRouteParams":         InvalidRouteParams,
+	"InvalidContentType":         InvalidContentType,
+	"NoReply":                    NoReply,
+	"NomiStillResponding":        StillResponding,
+	"NomiNotReady":               NotReady,
+	"OngoingVoiceCallDetected":   OngoingVoiceCallDetected,
+	"MessageLengthLimitExceeded": MessageLengthLimitExceeded,
+	"LimitExceeded":              LimitExceeded,
+	"InvalidBody":                InvalidBody,
+	"InsufficientPlan":           InsufficientPlan,
+	"ExceededRoomLimit":          ExceededRoomLimit,
+	"RoomNomiCountTooSmall":      RoomNomiCountTooSmall,
+	"RoomNomiCountTooLarge":      RoomNomiCountTooLarge,
+	"RoomNotFound":               RoomNotFound,
+	"RoomNomiNotFound":           RoomNomiNotFound,
+	"RoomStillCreating":          RoomStillCreating,
+	"RoomNomiNotReadyForMessage": RoomNomiNotReadyForMessage,
+}
+
+// ErrorTypes returns the error types of the Nomi API that match an error declared by this package, as sent in the
+// error.type key of failed responses, sorted
+func ErrorTypes() []string {
+	return slices.Sorted(maps.Keys(errorTypes))
+}
+
+// ErrorType names err for logs and metrics: the type sent by the Nomi API, e.g. "NomiNotFound", also for the errors
+// the client returns without calling the API, such as "LimitExceeded" once the daily quota is used. It returns
+// "Canceled" or "DeadlineExceeded" when the context ended the call, "UnexpectedResponse" for a response without a
+// type, "Other" for any other error and "" for nil
+func ErrorType(err error) string {
+	var apiErr *Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &apiErr) && apiErr.Type != "":
+		return apiErr.Type
+	case apiErr != nil:
+		return "UnexpectedResponse"
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "DeadlineExceeded"
+	}
+
+	for errorType, sentinel := range errorTypes {
+		if errors.Is(err, sentinel) {
+			return errorType
+		}
+	}
+
+	return "Other"
+}
+
+// contextError returns the context's error, wrapped, when a request was aborted
+// because ctx was canceled or its deadline expired. Otherwise, err is returned as is.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("request aborted: %w", ctxErr)
 	}
+
+	return err
 }
@@ -0,0 +1,177 @@
+// Package export renders conversations with a Nomi or in a Room to Markdown, standalone HTML, JSON and plain text,
+// for users to download them. Conversations are made of the entries of a nomi.HistoryStore:
+//
+//	entries, err := store.Query(ctx, nomi.HistoryQuery{RoomUUID: room.UUID})
+//	if err != nil {
+//		return err
+//	}
+//	err = export.Write(w, export.Markdown, export.Conversation{Room: &room, Entries: entries}, export.Options{})
+//
+// Messages are grouped by day and the replies are attributed to the Nomis of the Room, or to the Nomi of a one-to-one
+// conversation.
+package export
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Format is an output format of a transcript
+type Format string
+
+const (
+	Markdown Format = "markdown"
+	HTML     Format = "html"
+	JSON     Format = "json"
+	Text     Format = "text"
+)
+
+// Formats lists every supported format
+var Formats = []Format{Markdown, HTML, JSON, Text}
+
+// ParseFormat returns the format called name, also accepting the usual file extensions md, htm and txt
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "markdown", "md":
+		return Markdown, nil
+	case "html", "htm":
+		return HTML, nil
+	case "json":
+		return JSON, nil
+	case "text", "txt":
+		return Text, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q, expected markdown, html, json or text", name)
+	}
+}
+
+// Conversation is what is exported: the messages of a one-to-one chat with Nomi, or of Room
+type Conversation struct {
+	// Title defaults to the name of the Room or "Conversation with <Nomi>"
+	Title   string
+	Nomi    *nomi.Nomi
+	Room    *nomi.Room
+	Entries []nomi.HistoryEntry
+}
+
+// Options changes how a conversation is exported
+type Options struct {
+	// Since and Until keep the messages sent in this time range, Until being excluded. Zero values do not limit it
+	Since time.Time
+	Until time.Time
+	// Location is the time zone of the dates and times shown. Defaults to time.Local
+	Location *time.Location
+	// UserName is the speaker of the messages sent by the user. Defaults to "You"
+	UserName string
+}
+
+// Write renders c in format to w
+func Write(w io.Writer, format Format, c Conversation, opts Options) error {
+	t := newTranscript(c, opts)
+
+	switch format {
+	case Markdown:
+		return t.markdown(w)
+	case HTML:
+		return t.html(w)
+	case JSON:
+		return t.json(w)
+	case Text:
+		return t.text(w)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// transcript is the conversation as it is rendered
+type transcript struct {
+	Title    string     `json:"title"`
+	NomiUUID *uuid.UUID `json:"nomiUuid,omitempty"`
+	RoomUUID *uuid.UUID `json:"roomUuid,omitempty"`
+	Days     []day      `json:"days"`
+}
+
+type day struct {
+	Date     time.Time `json:"-"`
+	Day      string    `json:"date"`
+	Messages []line    `json:"messages"`
+}
+
+type line struct {
+	UUID      uuid.UUID      `json:"uuid"`
+	Sent      time.Time      `json:"sent"`
+	Speaker   string         `json:"speaker"`
+	Direction nomi.Direction `json:"direction"`
+	NomiUUID  *uuid.UUID     `json:"nomiUuid,omitempty"`
+	Text      string         `json:"text"`
+}
+
+func newTranscript(c Conversation, opts Options) transcript {
+	location := cmp.Or(opts.Location, time.Local)
+	userName := cmp.Or(opts.UserName, "You")
+
+	t := transcript{Title: c.Title, Days: []day{}}
+	names := map[uuid.UUID]string{}
+	if c.Nomi != nil {
+		t.Title = cmp.Or(t.Title, "Conversation with "+c.Nomi.Name)
+		t.NomiUUID = &c.Nomi.UUID
+		names[c.Nomi.UUID] = c.Nomi.Name
+	}
+	if c.Room != nil {
+		t.Title = cmp.Or(t.Title, c.Room.Name)
+		t.RoomUUID = &c.Room.UUID
+		for _, n := range c.Room.Nomis {
+			names[n.UUID] = n.Name
+		}
+	}
+	t.Title = cmp.Or(t.Title, "Conversation")
+
+	query := nomi.HistoryQuery{Since: opts.Since, Until: opts.Until}
+	entries := slices.Clone(c.Entries)
+	slices.SortStableFunc(entries, func(a, b nomi.HistoryEntry) int {
+		return a.Message.Sent.Compare(b.Message.Sent)
+	})
+
+	for _, entry := range entries {
+		if !query.Match(entry) {
+			continue
+		}
+
+		l := line{
+			UUID:      entry.Message.UUID,
+			Sent:      entry.Message.Sent.In(location),
+			Direction: entry.Direction,
+			Text:      entry.Message.Text,
+			Speaker:   userName,
+		}
+		if entry.Direction == nomi.DirectionReply {
+			id := entry.NomiUUID
+			l.NomiUUID = &id
+			l.Speaker = cmp.Or(names[id], "Nomi "+id.String())
+		}
+
+		year, month, date := l.Sent.Date()
+		d := time.Date(year, month, date, 0, 0, 0, 0, location)
+		if len(t.Days) == 0 || !t.Days[len(t.Days)-1].Date.Equal(d) {
+			t.Days = append(t.Days, day{Date: d, Day: d.Format(time.DateOnly)})
+		}
+		t.Days[len(t.Days)-1].Messages = append(t.Days[len(t.Days)-1].Messages, l)
+	}
+
+	return t
+}
+
+// heading is how a day is titled
+func (d day) heading() string {
+	return d.Date.Format("Monday, January 2, 2006")
+}
+
+func (l line) time() string {
+	return l.Sent.Format("15:04")
+}
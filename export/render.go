@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+func (t transcript) markdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n", escapeMarkdown(t.Title))
+	for _, d := range t.Days {
+		fmt.Fprintf(bw, "\n## %s\n", d.heading())
+		for _, l := range d.Messages {
+			// Two trailing spaces keep the line breaks of a message in the paragraph
+			text := strings.ReplaceAll(escapeMarkdown(l.Text), "\n", "  \n")
+			fmt.Fprintf(bw, "\n**%s** (%s): %s\n", escapeMarkdown(l.Speaker), l.time(), text)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// escapeMarkdown escapes the characters that would format text in Markdown
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "#", `\#`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "|", `\|`,
+)
+
+func (t transcript) text(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n", t.Title)
+	for _, d := range t.Days {
+		fmt.Fprintf(bw, "\n== %s ==\n", d.heading())
+		for _, l := range d.Messages {
+			fmt.Fprintf(bw, "[%s] %s: %s\n", l.time(), l.Speaker, l.Text)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (t transcript) json(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+func (t transcript) html(w io.Writer) error {
+	return htmlTemplate.Execute(w, t)
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"heading": day.heading,
+	"time":    line.time,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h2 { font-size: 1rem; color: #666; text-align: center; margin: 2rem 0 1rem; }
+.message { margin: 0.5rem 0; padding: 0.5rem 0.75rem; border-radius: 0.75rem; max-width: 80%; white-space: pre-wrap; }
+.sent { background: #d8ecff; margin-left: auto; }
+.reply { background: #f0f0f0; }
+.speaker { font-weight: bold; }
+time { color: #888; font-size: 0.8rem; margin-left: 0.5rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- range .Days}}
+<section>
+<h2>{{heading .}}</h2>
+{{- range .Messages}}
+<div class="message {{.Direction}}"><span class="speaker">{{.Speaker}}</span><time datetime="{{.Sent.Format "2006-01-02T15:04:05Z07:00"}}">{{time .}}</time>
+<div class="text">{{.Text}}</div></div>
+{{- end}}
+</section>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,213 @@
+package nomi
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Direction tells whether a message of the history was sent by the user or is the reply of a Nomi
+type Direction string
+
+const (
+	DirectionSent  Direction = "sent"
+	DirectionReply Direction = "reply"
+)
+
+// HistoryEntry is a message saved to a HistoryStore
+type HistoryEntry struct {
+	// NomiUUID is the Nomi the message was sent to or replied by. It is uuid.Nil for messages sent in a Room
+	NomiUUID uuid.UUID `json:"nomiUuid"`
+	// RoomUUID is the Room of the message, uuid.Nil for the main chat of a Nomi
+	RoomUUID  uuid.UUID `json:"roomUuid"`
+	Direction Direction `json:"direction"`
+	Message   Message   `json:"message"`
+}
+
+// HistoryQuery selects entries of a HistoryStore. Zero fields match every entry
+type HistoryQuery struct {
+	NomiUUID uuid.UUID
+	RoomUUID uuid.UUID
+	// Since and Until bound the time the messages were sent, Until being excluded
+	Since time.Time
+	Until time.Time
+	// Text matches the messages containing it, ignoring case
+	Text string
+	// Limit keeps only the Limit most recent matching entries
+	Limit int
+}
+
+// Match reports whether entry is selected by q
+func (q HistoryQuery) Match(entry HistoryEntry) bool {
+	return (q.NomiUUID == uuid.Nil || entry.NomiUUID == q.NomiUUID) &&
+		(q.RoomUUID == uuid.Nil || entry.RoomUUID == q.RoomUUID) &&
+		(q.Since.IsZero() || !entry.Message.Sent.Before(q.Since)) &&
+		(q.Until.IsZero() || entry.Message.Sent.Before(q.Until)) &&
+		(q.Text == "" || strings.Contains(strings.ToLower(entry.Message.Text), strings.ToLower(q.Text)))
+}
+
+// HistoryStore persists the messages of the conversations, since the Nomi API has no history endpoint
+type HistoryStore interface {
+	// Append saves entries
+	Append(ctx context.Context, entries ...HistoryEntry) error
+	// Query returns the entries selected by q, oldest first
+	Query(ctx context.Context, q HistoryQuery) ([]HistoryEntry, error)
+}
+
+// HistoryError is returned when a message was sent but could not be saved to the HistoryStore. The response is
+// returned along with it
+type HistoryError struct {
+	Err error
+}
+
+func (e *HistoryError) Error() string {
+	return fmt.Sprintf("message sent but not saved to the history: %s", e.Err)
+}
+
+func (e *HistoryError) Unwrap() error {
+	return e.Err
+}
+
+// WithHistory wraps client to save every message sent with SendMessage and SendRoomMessage, and every reply, to
+// store. Failed requests are not saved. The entries are saved by UUID, so wrap the history client to resolve names,
+// as in WithNameResolution(WithHistory(client, store), ttl); a message sent to a name is otherwise not saved and a
+// *HistoryError is returned
+func WithHistory(client API, store HistoryStore) API {
+	return &historyAPI{API: client, store: store}
+}
+
+type historyAPI struct {
+	API
+	store HistoryStore
+}
+
+func (h *historyAPI) save(ctx context.Context, entries ...HistoryEntry) error {
+	err := h.store.Append(ctx, entries...)
+	if err != nil {
+		return &HistoryError{Err: err}
+	}
+
+	return nil
+}
+
+// parseHistoryID parses the UUID under which the messages of a call to id are saved
+func parseHistoryID(kind string, id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, &HistoryError{Err: fmt.Errorf("%s %q is not a UUID, resolve names before the history client", kind, id)}
+	}
+
+	return parsed, nil
+}
+
+func (h *historyAPI) Quota() QuotaStats {
+	stats, _ := QuotaOf(h.API)
+	return stats
+}
+
+func (h *historyAPI) quotaOf() (QuotaStats, bool) {
+	return QuotaOf(h.API)
+}
+
+func (h *historyAPI) SendMessage(nomiID string, body SendMessageBody) (SendMessageResponse, error) {
+	return h.SendMessageContext(context.Background(), nomiID, body)
+}
+
+func (h *historyAPI) SendMessageContext(ctx context.Context, nomiID string, body SendMessageBody) (SendMessageResponse, error) {
+	res, err := h.API.SendMessageContext(ctx, nomiID, body)
+	if err != nil {
+		return res, err
+	}
+
+	id, err := parseHistoryID("Nomi", nomiID)
+	if err != nil {
+		return res, err
+	}
+	return res, h.save(ctx,
+		HistoryEntry{NomiUUID: id, Direction: DirectionSent, Message: res.SentMessage},
+		HistoryEntry{NomiUUID: id, Direction: DirectionReply, Message: res.ReplyMessage},
+	)
+}
+
+func (h *historyAPI) SendRoomMessage(roomID string, body SendRoomMessageBody) (SendRoomMessageResponse, error) {
+	return h.SendRoomMessageContext(context.Background(), roomID, body)
+}
+
+func (h *historyAPI) SendRoomMessageContext(ctx context.Context, roomID string, body SendRoomMessageBody) (SendRoomMessageResponse, error) {
+	res, err := h.API.SendRoomMessageContext(ctx, roomID, body)
+	if err != nil {
+		return res, err
+	}
+
+	id, err := parseHistoryID("Room", roomID)
+	if err != nil {
+		return res, err
+	}
+	return res, h.save(ctx, HistoryEntry{RoomUUID: id, Direction: DirectionSent, Message: res.SentMessage})
+}
+
+func (h *historyAPI) RequestNomiRoomMessage(roomID string, body RequestNomiRoomMessageBody) (RequestNomiMessageResponse, error) {
+	return h.RequestNomiRoomMessageContext(context.Background(), roomID, body)
+}
+
+func (h *historyAPI) RequestNomiRoomMessageContext(ctx context.Context, roomID string, body RequestNomiRoomMessageBody) (RequestNomiMessageResponse, error) {
+	res, err := h.API.RequestNomiRoomMessageContext(ctx, roomID, body)
+	if err != nil {
+		return res, err
+	}
+
+	id, err := parseHistoryID("Room", roomID)
+	if err != nil {
+		return res, err
+	}
+	return res, h.save(ctx, HistoryEntry{NomiUUID: body.NomiUUID, RoomUUID: id, Direction: DirectionReply, Message: res.ReplyMessage})
+}
+
+// MemoryHistory is a HistoryStore keeping the entries in memory
+type MemoryHistory struct {
+	mu      sync.Mutex
+	entries []HistoryEntry
+}
+
+// NewMemoryHistory creates an empty MemoryHistory
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{}
+}
+
+func (m *MemoryHistory) Append(ctx context.Context, entries ...HistoryEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = append(m.entries, entries...)
+	return nil
+}
+
+func (m *MemoryHistory) Query(ctx context.Context, q HistoryQuery) ([]HistoryEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return filterHistory(m.entries, q), nil
+}
+
+// filterHistory returns the entries selected by q, sorted by the time they were sent
+func filterHistory(entries []HistoryEntry, q HistoryQuery) []HistoryEntry {
+	var selected []HistoryEntry
+	for _, entry := range entries {
+		if q.Match(entry) {
+			selected = append(selected, entry)
+		}
+	}
+
+	slices.SortStableFunc(selected, func(a, b HistoryEntry) int {
+		return a.Message.Sent.Compare(b.Message.Sent)
+	})
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[len(selected)-q.Limit:]
+	}
+
+	return selected
+}
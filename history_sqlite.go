@@ -0,0 +1,127 @@
+package nomi
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SQLiteHistory is a HistoryStore saving the entries to a SQLite database. It works with any database/sql driver for
+// SQLite, such as modernc.org/sqlite or github.com/mattn/go-sqlite3, which the caller imports and opens
+type SQLiteHistory struct {
+	db *sql.DB
+}
+
+// NewSQLiteHistory creates a SQLiteHistory using db, creating the nomi_history table if it does not exist
+func NewSQLiteHistory(ctx context.Context, db *sql.DB) (*SQLiteHistory, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS nomi_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	nomi_uuid TEXT NOT NULL,
+	room_uuid TEXT NOT NULL,
+	direction TEXT NOT NULL,
+	message_uuid TEXT NOT NULL,
+	text TEXT NOT NULL,
+	sent INTEGER NOT NULL
+)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS nomi_history_sent ON nomi_history (sent)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLiteHistory{db: db}, nil
+}
+
+func (s *SQLiteHistory) Append(ctx context.Context, entries ...HistoryEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO nomi_history (nomi_uuid, room_uuid, direction, message_uuid, text, sent) VALUES (?, ?, ?, ?, ?, ?)`,
+			entry.NomiUUID.String(), entry.RoomUUID.String(), string(entry.Direction), entry.Message.UUID.String(),
+			entry.Message.Text, entry.Message.Sent.UnixNano(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteHistory) Query(ctx context.Context, q HistoryQuery) ([]HistoryEntry, error) {
+	var where []string
+	var args []any
+	if q.NomiUUID != uuid.Nil {
+		where = append(where, "nomi_uuid = ?")
+		args = append(args, q.NomiUUID.String())
+	}
+	if q.RoomUUID != uuid.Nil {
+		where = append(where, "room_uuid = ?")
+		args = append(args, q.RoomUUID.String())
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "sent >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "sent < ?")
+		args = append(args, q.Until.UnixNano())
+	}
+
+	query := `SELECT nomi_uuid, room_uuid, direction, message_uuid, text, sent FROM nomi_history`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY sent DESC, id DESC"
+	// SQLite only folds the case of ASCII letters, so the text is matched below like the other stores do
+	if q.Limit > 0 && q.Text == "" {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		var nomiID, roomID, direction, messageID string
+		var sent int64
+		err := rows.Scan(&nomiID, &roomID, &direction, &messageID, &entry.Message.Text, &sent)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.NomiUUID, _ = uuid.Parse(nomiID)
+		entry.RoomUUID, _ = uuid.Parse(roomID)
+		entry.Message.UUID, _ = uuid.Parse(messageID)
+		entry.Direction = Direction(direction)
+		entry.Message.Sent = time.Unix(0, sent).UTC()
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The query returns the most recent entries first so that Limit keeps them
+	slices.Reverse(entries)
+	if q.Text != "" {
+		entries = filterHistory(entries, q)
+	}
+
+	return entries, nil
+}
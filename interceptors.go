@@ -0,0 +1,64 @@
+package nomi
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Call is a call to the Nomi API as seen by the interceptors
+type Call struct {
+	// Operation is the name of the API method making the call, e.g. "SendMessage"
+	Operation string
+	Method    string
+	URL       string
+	// NomiID and RoomID are the UUIDs of the Nomi and the Room the call is about, if any
+	NomiID string
+	RoomID string
+	// Body is the request body, e.g. a SendMessageBody, or nil when there is none. It is read-only: the body is
+	// encoded from the parameters of the method, so changing it does not change the request
+	Body any
+	// Header is sent with every attempt at the call, after the headers of the client, so setting Authorization here
+	// replaces the API key
+	Header http.Header
+	// Result is the decoded response body, e.g. a *SendMessageResponse, once the call has succeeded
+	Result any
+	// StatusCode is the status of the last response, or zero if none was received
+	StatusCode int
+	// Attempts is the number of requests sent, more than one when the call was retried
+	Attempts int
+	// Duration is the time the call took, including the retries and waits
+	Duration time.Duration
+}
+
+// Interceptor hooks into every call made by the client. Any of the hooks may be nil
+type Interceptor struct {
+	// BeforeSend is called before the first attempt at the call. It may change call.Header and return a context
+	// used for the rest of the call, or nil to keep ctx. An error aborts the call and is returned to the caller
+	BeforeSend func(ctx context.Context, call *Call) (context.Context, error)
+	// AfterReceive is called once the call has succeeded, with the decoded response in call.Result
+	AfterReceive func(ctx context.Context, call *Call)
+	// OnError is called once the call has failed, after the retries, with the error returned to the caller
+	OnError func(ctx context.Context, call *Call, err error)
+}
+
+// WithInterceptors adds interceptors to the client. BeforeSend hooks run in the order the interceptors were added,
+// AfterReceive and OnError hooks in the reverse order. When a BeforeSend hook fails, the OnError hooks of the
+// interceptors before it still run
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(a *api) {
+		a.interceptors = append(a.interceptors, interceptors...)
+	}
+}
+
+// intercepted runs the AfterReceive or OnError hooks of interceptors, in reverse order
+func intercepted(ctx context.Context, interceptors []Interceptor, call *Call, err error) {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		switch {
+		case err == nil && interceptors[i].AfterReceive != nil:
+			interceptors[i].AfterReceive(ctx, call)
+		case err != nil && interceptors[i].OnError != nil:
+			interceptors[i].OnError(ctx, call, err)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Package cli implements the nomi command-line tool
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/vhalmd/nomi-go-sdk"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Exit codes of the nomi command, derived from the errors of the Nomi API
+const (
+	ExitOK          = 0
+	ExitError       = 1
+	ExitUsage       = 2
+	ExitNotFound    = 3
+	ExitInvalid     = 4
+	ExitLimit       = 5
+	ExitUnavailable = 6
+)
+
+const usage = `Usage: nomi <command> [flags] [arguments]
+
+Commands:
+  nomis list                     List the Nomis of the account
+  nomis get <nomi>               Show the details of a Nomi
+  chat send <nomi> <message>     Send a message to a Nomi and print its reply
+  chat repl <nomi>               Chat interactively with a Nomi
+  rooms list                     List the Rooms of the account
+  rooms get <room>               Show the details of a Room
+  rooms create --name <name> --nomi <nomi> [--nomi <nomi>...] [--note <note>] [--backchanneling]
+                                 Create a Room
+  rooms update <room> [--name <name>] [--note <note>] [--backchanneling=true|false] [--nomi <nomi>...]
+                                 Change the details of a Room
+  rooms delete <room>            Delete a Room
+  rooms send <room> <message>    Send a message in a Room
+  rooms request <room> <nomi>    Make a Nomi send a message in a Room
+  rooms repl <room>              Chat interactively in a Room, /help lists the chat commands
+  rooms apply <spec> [--dry-run] Create, update and delete Rooms to match a YAML or JSON spec
+
+Flags accepted by every command:
+  --api-key <key>                API key, defaults to $NOMI_API_KEY or the config file
+  --base-url <url>               API address, defaults to $NOMI_BASE_URL, the config file or the public API
+  --config <path>                Config file, defaults to <user config dir>/nomi/config.yaml
+  -o, --output table|json|yaml   Output format, table by default
+
+Nomis and Rooms can be given by UUID or by name. The config file is a YAML document with the api_key, base_url
+and output keys.
+
+Exit codes:
+  0  success
+  1  unexpected error
+  2  invalid command line
+  3  the Nomi, Room or Nomi in the Room was not found
+  4  the request was invalid (bad ID, ambiguous name, body or message length, wrong number of Nomis in a Room)
+  5  a limit was hit (daily messages, number of Rooms, plan not entitled to Rooms)
+  6  the Nomi or Room cannot answer right now (no reply, still responding, not ready, in a voice call)
+`
+
+// CLI runs the nomi command with its standard streams and environment
+type CLI struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Getenv func(key string) string
+}
+
+// New creates a CLI using the streams and environment of the process
+func New() *CLI {
+	return &CLI{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Getenv: os.Getenv,
+	}
+}
+
+// config holds the settings common to every command
+type config struct {
+	APIKey  string `yaml:"api_key"`
+	BaseURL string `yaml:"base_url"`
+	Output  string `yaml:"output"`
+
+	path string
+}
+
+// usageError is returned for invalid command lines
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+type command func(env *environment, args []string) error
+
+var commands = map[string]map[string]command{
+	"nomis": {
+		"list": listNomis,
+		"get":  getNomi,
+	},
+	"chat": {
+		"send": sendMessage,
+		"repl": openNomi,
+	},
+	"rooms": {
+		"list":    listRooms,
+		"get":     getRoom,
+		"create":  createRoom,
+		"update":  updateRoom,
+		"delete":  deleteRoom,
+		"send":    sendRoomMessage,
+		"request": requestNomiRoomMessage,
+		"repl":    openRoom,
+		"apply":   applyRooms,
+	},
+}
+
+// Run executes the command line args, without the program name, and returns the exit code
+func (c *CLI) Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(c.Stdout, usage)
+		return ExitOK
+	}
+
+	var cmd command
+	if group, ok := commands[args[0]]; ok && len(args) > 1 {
+		cmd = group[args[1]]
+	}
+	if cmd == nil {
+		fmt.Fprintf(c.Stderr, "nomi: unknown command %q\n\n%s", strings.Join(args[:min(len(args), 2)], " "), usage)
+		return ExitUsage
+	}
+
+	err := cmd(&environment{cli: c, name: args[0] + " " + args[1]}, args[2:])
+	if err == nil {
+		return ExitOK
+	}
+
+	fmt.Fprintf(c.Stderr, "nomi: %s\n", err)
+	return ExitCode(err)
+}
+
+// ExitCode maps an error returned by the SDK to the exit code of the command
+func ExitCode(err error) int {
+	var usageErr usageError
+	var ambiguous *nomi.AmbiguousNameError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr), errors.Is(err, flag.ErrHelp):
+		return ExitUsage
+	case errors.Is(err, nomi.NotFound), errors.Is(err, nomi.RoomNotFound), errors.Is(err, nomi.RoomNomiNotFound):
+		return ExitNotFound
+	case errors.As(err, &ambiguous),
+		errors.Is(err, nomi.InvalidRouteParams),
+		errors.Is(err, nomi.InvalidBody),
+		errors.Is(err, nomi.InvalidContentType),
+		errors.Is(err, nomi.MessageLengthLimitExceeded),
+		errors.Is(err, nomi.RoomNomiCountTooSmall),
+		errors.Is(err, nomi.RoomNomiCountTooLarge):
+		return ExitInvalid
+	case errors.Is(err, nomi.LimitExceeded), errors.Is(err, nomi.InsufficientPlan), errors.Is(err, nomi.ExceededRoomLimit):
+		return ExitLimit
+	case errors.Is(err, nomi.NoReply),
+		errors.Is(err, nomi.StillResponding),
+		errors.Is(err, nomi.NotReady),
+		errors.Is(err, nomi.OngoingVoiceCallDetected),
+		errors.Is(err, nomi.RoomStillCreating),
+		errors.Is(err, nomi.RoomNomiNotReadyForMessage):
+		return ExitUnavailable
+	default:
+		return ExitError
+	}
+}
+
+// environment is what a command needs besides its arguments: its flags, the config and the API client
+type environment struct {
+	cli  *CLI
+	name string
+	cfg  config
+	out  *printer
+}
+
+// flags creates the flag set of the command, with the flags common to every command
+func (e *environment) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("nomi "+e.name, flag.ContinueOnError)
+	fs.SetOutput(e.cli.Stderr)
+	fs.StringVar(&e.cfg.APIKey, "api-key", "", "API key")
+	fs.StringVar(&e.cfg.BaseURL, "base-url", "", "API address")
+	fs.StringVar(&e.cfg.path, "config", "", "config file")
+	fs.StringVar(&e.cfg.Output, "output", "", "output format: table, json or yaml")
+	fs.StringVar(&e.cfg.Output, "o", "", "output format: table, json or yaml")
+
+	return fs
+}
+
+// parse parses the command line of the command, which takes exactly nargs positional arguments, or at least nargs
+// when variadic is set. Flags may come before or after the arguments
+func (e *environment) parse(fs *flag.FlagSet, args []string, nargs int, variadic bool) ([]string, error) {
+	var positional []string
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, usageError{msg: err.Error()}
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(positional) < nargs || (!variadic && len(positional) > nargs) {
+		return nil, usagef("nomi %s: expected %d argument(s), got %d", e.name, nargs, len(positional))
+	}
+
+	err := e.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return positional, nil
+}
+
+// load completes the flags with the environment and the config file, then prepares the output
+func (e *environment) load() error {
+	path := e.cfg.path
+	if path == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "nomi", "config.yaml")
+		}
+	}
+
+	var file config
+	if path != "" {
+		b, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			err = yaml.Unmarshal(b, &file)
+			if err != nil {
+				return fmt.Errorf("invalid config file %s: %w", path, err)
+			}
+		case !errors.Is(err, os.ErrNotExist) || e.cfg.path != "":
+			return err
+		}
+	}
+
+	e.cfg.APIKey = firstNonEmpty(e.cfg.APIKey, e.cli.Getenv("NOMI_API_KEY"), file.APIKey)
+	e.cfg.BaseURL = firstNonEmpty(e.cfg.BaseURL, e.cli.Getenv("NOMI_BASE_URL"), file.BaseURL)
+	e.cfg.Output = firstNonEmpty(e.cfg.Output, file.Output, "table")
+
+	out, err := newPrinter(e.cli.Stdout, e.cfg.Output)
+	if err != nil {
+		return err
+	}
+	e.out = out
+
+	return nil
+}
+
+// client creates the API client from the config
+func (e *environment) client() (nomi.API, error) {
+	if e.cfg.APIKey == "" {
+		return nil, usagef("no API key: use --api-key, set NOMI_API_KEY or add api_key to the config file")
+	}
+
+	var opts []nomi.Option
+	if e.cfg.BaseURL != "" {
+		opts = append(opts, nomi.WithBaseURL(e.cfg.BaseURL))
+	}
+
+	return nomi.WithNameResolution(nomi.NewClient(e.cfg.APIKey, opts...), time.Minute), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
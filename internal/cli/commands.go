@@ -0,0 +1,360 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/provision"
+	"strconv"
+	"strings"
+)
+
+// nomiList is a repeatable flag collecting Nomis by UUID or name
+type nomiList []string
+
+func (l *nomiList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *nomiList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// resolve returns the UUIDs of the Nomis of the list, listing the Nomis of the account only when names are given
+func (l nomiList) resolve(ctx context.Context, client nomi.API) ([]uuid.UUID, error) {
+	var nomis []nomi.Nomi
+	ids := make([]uuid.UUID, 0, len(l))
+	for _, ref := range l {
+		if id, err := uuid.Parse(ref); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+
+		if nomis == nil {
+			res, err := client.GetNomisContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			nomis = res.Nomis
+		}
+		n, err := nomi.FindNomi(nomis, ref)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, n.UUID)
+	}
+
+	return ids, nil
+}
+
+// isSet reports whether the flag called name was given on the command line
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+func listNomis(env *environment, args []string) error {
+	fs := env.flags()
+	if _, err := env.parse(fs, args, 0, false); err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	res, err := client.GetNomis()
+	if err != nil {
+		return err
+	}
+
+	return env.out.nomis(res)
+}
+
+func getNomi(env *environment, args []string) error {
+	fs := env.flags()
+	positional, err := env.parse(fs, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	res, err := client.GetNomi(positional[0])
+	if err != nil {
+		return err
+	}
+
+	return env.out.nomi(nomi.Nomi(res))
+}
+
+func sendMessage(env *environment, args []string) error {
+	fs := env.flags()
+	positional, err := env.parse(fs, args, 2, true)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	body := nomi.SendMessageBody{MessageText: strings.Join(positional[1:], " ")}
+	res, err := client.SendMessage(positional[0], body)
+	if err != nil {
+		return err
+	}
+
+	return env.out.messages(res, []messageRow{{"sent", res.SentMessage}, {"reply", res.ReplyMessage}})
+}
+
+func listRooms(env *environment, args []string) error {
+	fs := env.flags()
+	if _, err := env.parse(fs, args, 0, false); err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	res, err := client.GetRooms()
+	if err != nil {
+		return err
+	}
+
+	return env.out.rooms(res)
+}
+
+func getRoom(env *environment, args []string) error {
+	fs := env.flags()
+	positional, err := env.parse(fs, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	res, err := client.GetRoom(positional[0])
+	if err != nil {
+		return err
+	}
+
+	return env.out.room(nomi.Room(res))
+}
+
+func createRoom(env *environment, args []string) error {
+	var body nomi.CreateRoomBody
+	var nomis nomiList
+
+	fs := env.flags()
+	fs.StringVar(&body.Name, "name", "", "name of the Room")
+	fs.StringVar(&body.Note, "note", "", "note describing the Room")
+	fs.BoolVar(&body.BackchannelingEnabled, "backchanneling", false, "enable backchanneling")
+	fs.Var(&nomis, "nomi", "UUID or name of a Nomi to add to the Room, can be repeated")
+	if _, err := env.parse(fs, args, 0, false); err != nil {
+		return err
+	}
+
+	if body.Name == "" {
+		return usagef("nomi rooms create: --name is required")
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	body.NomiUUIDs, err = nomis.resolve(context.Background(), client)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.CreateRoom(body)
+	if err != nil {
+		return err
+	}
+
+	return env.out.room(nomi.Room(res))
+}
+
+func updateRoom(env *environment, args []string) error {
+	var name, note, backchanneling string
+	var nomis nomiList
+
+	fs := env.flags()
+	fs.StringVar(&name, "name", "", "new name of the Room")
+	fs.StringVar(&note, "note", "", "new note of the Room")
+	fs.StringVar(&backchanneling, "backchanneling", "", "enable or disable backchanneling: true or false")
+	fs.Var(&nomis, "nomi", "UUID or name of a Nomi in the Room, can be repeated. Replaces the current Nomis")
+	positional, err := env.parse(fs, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	var body nomi.UpdateRoomBody
+	if isSet(fs, "name") {
+		body.Name = &name
+	}
+	if isSet(fs, "note") {
+		body.Note = &note
+	}
+	if isSet(fs, "backchanneling") {
+		enabled, err := strconv.ParseBool(backchanneling)
+		if err != nil {
+			return usagef("nomi rooms update: --backchanneling must be true or false")
+		}
+		body.BackchannelingEnabled = &enabled
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	if len(nomis) > 0 {
+		body.NomiUUIDs, err = nomis.resolve(context.Background(), client)
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := client.UpdateRoom(positional[0], body)
+	if err != nil {
+		return err
+	}
+
+	return env.out.room(nomi.Room(res))
+}
+
+func deleteRoom(env *environment, args []string) error {
+	fs := env.flags()
+	positional, err := env.parse(fs, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	success, err := client.DeleteRoom(positional[0])
+	if err != nil {
+		return err
+	}
+	if !success {
+		return fmt.Errorf("the room %s was not deleted", positional[0])
+	}
+
+	return env.out.deleted(positional[0])
+}
+
+func sendRoomMessage(env *environment, args []string) error {
+	fs := env.flags()
+	positional, err := env.parse(fs, args, 2, true)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	body := nomi.SendRoomMessageBody{MessageText: strings.Join(positional[1:], " ")}
+	res, err := client.SendRoomMessage(positional[0], body)
+	if err != nil {
+		return err
+	}
+
+	return env.out.messages(res, []messageRow{{"sent", res.SentMessage}})
+}
+
+func requestNomiRoomMessage(env *environment, args []string) error {
+	fs := env.flags()
+	positional, err := env.parse(fs, args, 2, false)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	// the Nomi is looked up among the members of the Room, found by the name resolving client
+	room, err := client.GetRoom(positional[0])
+	if err != nil {
+		return err
+	}
+	n, err := nomi.FindNomi(room.Nomis, positional[1])
+	if err != nil {
+		return err
+	}
+
+	res, err := client.RequestNomiRoomMessage(room.UUID.String(), nomi.RequestNomiRoomMessageBody{NomiUUID: n.UUID})
+	if err != nil {
+		return err
+	}
+
+	return env.out.messages(res, []messageRow{{"reply", res.ReplyMessage}})
+}
+
+func applyRooms(env *environment, args []string) error {
+	var dryRun bool
+
+	fs := env.flags()
+	fs.BoolVar(&dryRun, "dry-run", false, "print the plan without applying it")
+	positional, err := env.parse(fs, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	spec, err := provision.Load(positional[0])
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	plan, err := provision.NewPlan(ctx, client, spec)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(env.cli.Stdout, plan)
+	if dryRun || len(plan.Changes) == 0 {
+		return nil
+	}
+
+	err = plan.Apply(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(env.cli.Stdout, "Applied.")
+	return err
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/vhalmd/nomi-go-sdk"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// printer writes the results of the commands in the chosen output format
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table", "json", "yaml":
+		return &printer{w: w, format: format}, nil
+	default:
+		return nil, usagef("unknown output format %q, expected table, json or yaml", format)
+	}
+}
+
+// structured writes v as JSON or YAML, and reports whether it did. The YAML output uses the JSON keys
+func (p *printer) structured(v any) (bool, error) {
+	switch p.format {
+	case "json":
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return true, enc.Encode(v)
+	case "yaml":
+		b, err := json.Marshal(v)
+		if err != nil {
+			return true, err
+		}
+
+		var node yaml.Node
+		err = yaml.Unmarshal(b, &node)
+		if err != nil {
+			return true, err
+		}
+		blockStyle(&node)
+
+		enc := yaml.NewEncoder(p.w)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return true, err
+		}
+		return true, enc.Close()
+	default:
+		return false, nil
+	}
+}
+
+// blockStyle removes the flow style and quotes JSON documents are parsed with
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// table writes rows as aligned columns
+func (p *printer) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	if header != nil {
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func nomiNames(nomis []nomi.Nomi) string {
+	var names []string
+	for _, n := range nomis {
+		names = append(names, n.Name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func (p *printer) nomis(res nomi.GetNomisResponse) error {
+	if ok, err := p.structured(res); ok {
+		return err
+	}
+
+	var rows [][]string
+	for _, n := range res.Nomis {
+		rows = append(rows, []string{n.UUID.String(), n.Name, string(n.Gender), string(n.RelationshipType), formatTime(n.Created)})
+	}
+
+	return p.table([]string{"UUID", "NAME", "GENDER", "RELATIONSHIP", "CREATED"}, rows)
+}
+
+func (p *printer) nomi(n nomi.Nomi) error {
+	if ok, err := p.structured(n); ok {
+		return err
+	}
+
+	return p.table(nil, [][]string{
+		{"UUID:", n.UUID.String()},
+		{"Name:", n.Name},
+		{"Gender:", string(n.Gender)},
+		{"Relationship:", string(n.RelationshipType)},
+		{"Created:", formatTime(n.Created)},
+	})
+}
+
+func (p *printer) rooms(res nomi.GetRoomsResponse) error {
+	if ok, err := p.structured(res); ok {
+		return err
+	}
+
+	var rows [][]string
+	for _, room := range res.Rooms {
+		rows = append(rows, []string{room.UUID.String(), room.Name, string(room.Status), nomiNames(room.Nomis), formatTime(room.Updated)})
+	}
+
+	return p.table([]string{"UUID", "NAME", "STATUS", "NOMIS", "UPDATED"}, rows)
+}
+
+func (p *printer) room(room nomi.Room) error {
+	if ok, err := p.structured(room); ok {
+		return err
+	}
+
+	return p.table(nil, [][]string{
+		{"UUID:", room.UUID.String()},
+		{"Name:", room.Name},
+		{"Status:", string(room.Status)},
+		{"Note:", room.Note},
+		{"Backchanneling:", fmt.Sprint(room.BackchannelingEnabled)},
+		{"Nomis:", nomiNames(room.Nomis)},
+		{"Created:", formatTime(room.Created)},
+		{"Updated:", formatTime(room.Updated)},
+	})
+}
+
+// messageRow is a message shown in the table output, with what it is to the user
+type messageRow struct {
+	kind string
+	msg  nomi.Message
+}
+
+// messages writes the response res of a chat command, showing rows in the table output
+func (p *printer) messages(res any, rows []messageRow) error {
+	if ok, err := p.structured(res); ok {
+		return err
+	}
+
+	var lines [][]string
+	for _, row := range rows {
+		lines = append(lines, []string{strings.ToUpper(row.kind), formatTime(row.msg.Sent), row.msg.Text})
+	}
+
+	return p.table(nil, lines)
+}
+
+func (p *printer) deleted(roomID string) error {
+	if ok, err := p.structured(map[string]any{"uuid": roomID, "deleted": true}); ok {
+		return err
+	}
+
+	_, err := fmt.Fprintf(p.w, "Deleted room %s\n", roomID)
+	return err
+}
@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/vhalmd/nomi-go-sdk"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+const replHelp = `Type a message and press enter to send it. Commands:
+  /who             show who is in the conversation
+  /ask [nomi]      make a Nomi of the Room reply, by name or UUID, or every Nomi in turn
+  /note [text]     show or change the note of the Room
+  /rename <name>   rename the Room
+  /help            show this help
+  /quit            leave
+`
+
+// lineReader reads the lines typed by the user
+type lineReader interface {
+	ReadLine() (string, error)
+}
+
+// scanner reads lines from a stream that is not a terminal, e.g. a pipe
+type scanner struct {
+	*bufio.Scanner
+}
+
+func (s scanner) ReadLine() (string, error) {
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+
+	return s.Text(), nil
+}
+
+// repl is an interactive session with a Nomi or a Room
+type repl struct {
+	client nomi.API
+	out    io.Writer
+	// nomi is the Nomi of a one-to-one session, room the Room of a Room session
+	nomi *nomi.Nomi
+	room *nomi.Room
+}
+
+func openNomi(env *environment, args []string) error {
+	fs := env.flags()
+	positional, err := env.parse(fs, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	n, err := client.GetNomi(positional[0])
+	if err != nil {
+		return err
+	}
+
+	return env.runREPL(&repl{client: client, nomi: (*nomi.Nomi)(&n)})
+}
+
+func openRoom(env *environment, args []string) error {
+	fs := env.flags()
+	positional, err := env.parse(fs, args, 1, false)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+
+	room, err := client.GetRoom(positional[0])
+	if err != nil {
+		return err
+	}
+
+	return env.runREPL(&repl{client: client, room: (*nomi.Room)(&room)})
+}
+
+// runREPL reads lines until the user quits. When stdin is a terminal, it is put in raw mode for line editing and
+// history, restored on return
+func (e *environment) runREPL(r *repl) error {
+	var lines lineReader
+	r.out = e.cli.Stdout
+
+	if f, ok := e.cli.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		state, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return err
+		}
+		defer term.Restore(int(f.Fd()), state)
+
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{f, e.cli.Stdout}, "you> ")
+		lines = t
+		r.out = t
+	} else {
+		lines = scanner{bufio.NewScanner(e.cli.Stdin)}
+	}
+
+	r.printf("%s\n", r.title())
+	r.printf("Type /help for the list of commands.\n")
+
+	for {
+		line, err := lines.ReadLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if r.handle(context.Background(), strings.TrimSpace(line)) {
+			return nil
+		}
+	}
+}
+
+func (r *repl) printf(format string, args ...any) {
+	fmt.Fprintf(r.out, format, args...)
+}
+
+func (r *repl) title() string {
+	if r.room != nil {
+		return fmt.Sprintf("Room %s with %s", r.room.Name, nomiNames(r.room.Nomis))
+	}
+
+	return fmt.Sprintf("Chatting with %s", r.nomi.Name)
+}
+
+// message prints a message with the time it was sent
+func (r *repl) message(author string, msg nomi.Message) {
+	r.printf("[%s] %s: %s\n", msg.Sent.Local().Format("15:04:05"), author, msg.Text)
+}
+
+// status prints why a request failed instead of stopping the session
+func (r *repl) status(who string, err error) {
+	switch {
+	case errors.Is(err, nomi.StillResponding), errors.Is(err, nomi.RoomNomiNotReadyForMessage):
+		r.printf("* %s is still replying to another message, try again in a moment\n", who)
+	case errors.Is(err, nomi.NoReply):
+		r.printf("* %s did not reply, try again\n", who)
+	case errors.Is(err, nomi.NotReady), errors.Is(err, nomi.RoomStillCreating):
+		r.printf("* %s is not ready yet, try again in a few seconds\n", who)
+	case errors.Is(err, nomi.OngoingVoiceCallDetected):
+		r.printf("* %s is in a voice call and cannot reply to messages\n", who)
+	case errors.Is(err, nomi.LimitExceeded):
+		r.printf("* the daily message quota is used up\n")
+	case errors.Is(err, nomi.MessageLengthLimitExceeded):
+		r.printf("* the message is too long\n")
+	default:
+		r.printf("* error: %s\n", err)
+	}
+}
+
+// handle runs a line typed by the user and reports whether the session is over
+func (r *repl) handle(ctx context.Context, line string) bool {
+	if line == "" {
+		return false
+	}
+	if !strings.HasPrefix(line, "/") {
+		r.send(ctx, line)
+		return false
+	}
+
+	command, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch command {
+	case "/quit", "/exit":
+		return true
+	case "/help":
+		r.printf("%s", replHelp)
+	case "/who":
+		r.who()
+	case "/ask":
+		r.ask(ctx, arg)
+	case "/note":
+		r.note(ctx, arg)
+	case "/rename":
+		r.rename(ctx, arg)
+	default:
+		r.printf("* unknown command %s, type /help for the list of commands\n", command)
+	}
+
+	return false
+}
+
+func (r *repl) send(ctx context.Context, text string) {
+	if r.room == nil {
+		res, err := r.client.SendMessageContext(ctx, r.nomi.UUID.String(), nomi.SendMessageBody{MessageText: text})
+		if err != nil {
+			r.status(r.nomi.Name, err)
+			return
+		}
+
+		r.message("you", res.SentMessage)
+		r.message(r.nomi.Name, res.ReplyMessage)
+		return
+	}
+
+	res, err := r.client.SendRoomMessageContext(ctx, r.room.UUID.String(), nomi.SendRoomMessageBody{MessageText: text})
+	if err != nil {
+		r.status(r.room.Name, err)
+		return
+	}
+	r.message("you", res.SentMessage)
+}
+
+func (r *repl) who() {
+	if r.room == nil {
+		r.printf("* %s (%s, %s)\n", r.nomi.Name, r.nomi.Gender, r.nomi.RelationshipType)
+		return
+	}
+
+	for _, n := range r.room.Nomis {
+		r.printf("* %s (%s)\n", n.Name, n.UUID)
+	}
+}
+
+func (r *repl) ask(ctx context.Context, nameOrID string) {
+	if r.room == nil {
+		r.printf("* /ask only works in Rooms, %s replies to every message\n", r.nomi.Name)
+		return
+	}
+
+	nomis := r.room.Nomis
+	if nameOrID != "" {
+		n, err := nomi.FindNomi(r.room.Nomis, nameOrID)
+		if errors.Is(err, nomi.NotFound) {
+			r.printf("* there is no %s in this Room, type /who to see who is here\n", nameOrID)
+			return
+		}
+		if err != nil {
+			r.printf("* %s\n", err)
+			return
+		}
+		nomis = []nomi.Nomi{n}
+	}
+
+	for _, n := range nomis {
+		res, err := r.client.RequestNomiRoomMessageContext(ctx, r.room.UUID.String(), nomi.RequestNomiRoomMessageBody{NomiUUID: n.UUID})
+		if err != nil {
+			r.status(n.Name, err)
+			continue
+		}
+		r.message(n.Name, res.ReplyMessage)
+	}
+}
+
+func (r *repl) note(ctx context.Context, note string) {
+	if r.room == nil {
+		r.printf("* /note only works in Rooms\n")
+		return
+	}
+	if note == "" {
+		r.printf("* note: %s\n", r.room.Note)
+		return
+	}
+
+	r.update(ctx, nomi.UpdateRoomBody{Note: &note})
+}
+
+func (r *repl) rename(ctx context.Context, name string) {
+	if r.room == nil {
+		r.printf("* /rename only works in Rooms\n")
+		return
+	}
+	if name == "" {
+		r.printf("* usage: /rename <name>\n")
+		return
+	}
+
+	r.update(ctx, nomi.UpdateRoomBody{Name: &name})
+}
+
+func (r *repl) update(ctx context.Context, body nomi.UpdateRoomBody) {
+	res, err := r.client.UpdateRoomContext(ctx, r.room.UUID.String(), body)
+	if err != nil {
+		r.status(r.room.Name, err)
+		return
+	}
+
+	*r.room = nomi.Room(res)
+	r.printf("* %s updated\n", r.room.Name)
+}
@@ -0,0 +1,205 @@
+package nomi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithRateLimit makes the client wait before sending a request once more than burst requests were sent at a rate
+// above perSecond. Every attempt counts, retries included. Create a single client per API key and share it so that
+// the limit applies to the key as a whole
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(a *api) {
+		if perSecond <= 0 {
+			a.limiter = nil
+			return
+		}
+
+		a.limiter = newTokenBucket(perSecond, max(burst, 1))
+	}
+}
+
+// WithDailyQuota makes the client refuse to send more than limit messages per UTC day with SendMessage,
+// SendRoomMessage and RequestNomiRoomMessage. Once the quota is used, these methods return a *QuotaExceededError
+// without calling the API
+func WithDailyQuota(limit int) Option {
+	return func(a *api) {
+		a.quota.limit = limit
+	}
+}
+
+// QuotaStats is the daily message usage tracked by the client
+type QuotaStats struct {
+	// Limit is the quota set with WithDailyQuota, zero when there is none
+	Limit int
+	// Used is the number of messages sent since the start of the current UTC day
+	Used int
+	// Remaining is the number of messages that can still be sent today, zero when there is no quota
+	Remaining int
+	// ResetAt is when the usage goes back to zero, at the start of the next UTC day
+	ResetAt time.Time
+}
+
+// QuotaExceededError is returned instead of calling the API when the daily message quota is used.
+// It matches LimitExceeded with errors.Is
+type QuotaExceededError struct {
+	Limit   int
+	Used    int
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("daily message quota of %d is exhausted (%d used), resets in %s", e.Limit, e.Used, e.ResetIn().Round(time.Second))
+}
+
+// ResetIn returns how long until messages can be sent again
+func (e *QuotaExceededError) ResetIn() time.Duration {
+	return max(time.Until(e.ResetAt), 0)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return LimitExceeded
+}
+
+// quotaTracker counts the messages sent during the current UTC day
+type quotaTracker struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+	day   time.Time
+}
+
+// rollover resets the usage when a new UTC day started. It must be called with mu held
+func (q *quotaTracker) rollover(now time.Time) {
+	day := now.UTC().Truncate(24 * time.Hour)
+	if !day.Equal(q.day) {
+		q.day = day
+		q.used = 0
+	}
+}
+
+// reserve counts a message about to be sent, failing if the quota is already used
+func (q *quotaTracker) reserve() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollover(time.Now())
+	if q.limit > 0 && q.used >= q.limit {
+		return &QuotaExceededError{Limit: q.limit, Used: q.used, ResetAt: q.day.Add(24 * time.Hour)}
+	}
+	q.used++
+
+	return nil
+}
+
+// release updates the usage once the message was sent, or not, with err
+func (q *quotaTracker) release(err error) {
+	if err == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollover(time.Now())
+	switch {
+	case errors.Is(err, LimitExceeded) && q.limit > 0:
+		// the server knows better, consider the quota used until the end of the day
+		q.used = max(q.used, q.limit)
+	case q.used > 0:
+		q.used--
+	}
+}
+
+func (q *quotaTracker) stats() QuotaStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollover(time.Now())
+	stats := QuotaStats{
+		Limit:   q.limit,
+		Used:    q.used,
+		ResetAt: q.day.Add(24 * time.Hour),
+	}
+	if q.limit > 0 {
+		stats.Remaining = max(q.limit-q.used, 0)
+	}
+
+	return stats
+}
+
+// QuotaReporter is implemented by the clients created by NewClient, and by the decorators of this package wrapping
+// them, to report their daily message usage
+type QuotaReporter interface {
+	// Quota returns the number of messages sent today with SendMessage, SendRoomMessage and RequestNomiRoomMessage,
+	// and the quota set with WithDailyQuota
+	Quota() QuotaStats
+}
+
+// QuotaOf returns the daily message usage of client, or false if client does not report it
+func QuotaOf(client API) (QuotaStats, bool) {
+	if decorator, ok := client.(quotaForwarder); ok {
+		return decorator.quotaOf()
+	}
+
+	reporter, ok := client.(QuotaReporter)
+	if !ok {
+		return QuotaStats{}, false
+	}
+
+	return reporter.Quota(), true
+}
+
+// quotaForwarder is implemented by the decorators of this package, which report the usage of the client they wrap
+// only if that client reports it
+type quotaForwarder interface {
+	quotaOf() (QuotaStats, bool)
+}
+
+func (a api) Quota() QuotaStats {
+	return a.quota.stats()
+}
+
+// tokenBucket is a rate limiter holding up to burst tokens, refilled at rate tokens per second
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token from the bucket, waiting for one to be available unless ctx is done first
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package nomi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// LogOption changes what WithLogger logs
+type LogOption func(*requestLogger)
+
+// WithLogLevels sets the levels of the requests that succeed and of those that fail. Defaults to slog.LevelInfo and
+// slog.LevelError
+func WithLogLevels(success slog.Level, failure slog.Level) LogOption {
+	return func(l *requestLogger) {
+		l.success = success
+		l.failure = failure
+	}
+}
+
+// WithBodies also logs the headers and the bodies of the requests and responses, which hold the messages, in a
+// separate record at slog.LevelDebug
+func WithBodies() LogOption {
+	return func(l *requestLogger) {
+		l.bodies = true
+	}
+}
+
+// WithLogger makes the client log each request it sends to logger, with its operation, method, path, attempt,
+// status and duration, and the error type given by ErrorType when it fails. The Authorization header is never logged
+func WithLogger(logger *slog.Logger, opts ...LogOption) Option {
+	return func(a *api) {
+		if logger == nil {
+			a.logger = nil
+			return
+		}
+
+		l := &requestLogger{logger: logger, success: slog.LevelInfo, failure: slog.LevelError}
+		for _, opt := range opts {
+			opt(l)
+		}
+		a.logger = l
+	}
+}
+
+// requestLogger logs the requests sent by the client
+type requestLogger struct {
+	logger  *slog.Logger
+	success slog.Level
+	failure slog.Level
+	bodies  bool
+}
+
+func (l *requestLogger) log(ctx context.Context, call *Call, req *http.Request, status int, duration time.Duration,
+	payload []byte, response []byte, err error) {
+	level := l.success
+	if err != nil {
+		level = l.failure
+	}
+
+	if l.logger.Enabled(ctx, level) {
+		attrs := []slog.Attr{
+			slog.String("operation", call.Operation),
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.Int("attempt", call.Attempts),
+			slog.Int("status", status),
+			slog.Duration("duration", duration),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error_type", ErrorType(err)), slog.String("error", err.Error()))
+		}
+		l.logger.LogAttrs(ctx, level, "nomi request", attrs...)
+	}
+
+	if l.bodies && l.logger.Enabled(ctx, slog.LevelDebug) {
+		l.logger.LogAttrs(ctx, slog.LevelDebug, "nomi request bodies",
+			slog.String("operation", call.Operation),
+			slog.Int("attempt", call.Attempts),
+			redactedHeaders(req.Header),
+			slog.String("request_body", string(payload)),
+			slog.String("response_body", string(response)),
+		)
+	}
+}
+
+// rejected logs a call that failed before any request was sent, e.g. because of an invalid ID or the daily quota
+func (l *requestLogger) rejected(ctx context.Context, call *Call, err error) {
+	if !l.logger.Enabled(ctx, l.failure) {
+		return
+	}
+
+	path := call.URL
+	if u, parseErr := url.Parse(call.URL); parseErr == nil {
+		path = u.Path
+	}
+	l.logger.LogAttrs(ctx, l.failure, "nomi request",
+		slog.String("operation", call.Operation),
+		slog.String("method", call.Method),
+		slog.String("path", path),
+		slog.Int("attempt", 0),
+		slog.Int("status", 0),
+		slog.Duration("duration", call.Duration),
+		slog.String("error_type", ErrorType(err)),
+		slog.String("error", err.Error()),
+	)
+}
+
+// redactedHeaders returns the headers as a group, with the value of Authorization hidden
+func redactedHeaders(header http.Header) slog.Attr {
+	var attrs []any
+	for key, values := range header {
+		value := strings.Join(values, ", ")
+		if http.CanonicalHeaderKey(key) == "Authorization" {
+			value = "REDACTED"
+		}
+		attrs = append(attrs, slog.String(key, value))
+	}
+
+	return slog.Group("headers", attrs...)
+}
@@ -0,0 +1,173 @@
+package nomi
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Plan is the kind of Nomi account the API key belongs to, which determines how long messages can be
+type Plan string
+
+const (
+	PlanFree       Plan = "Free"
+	PlanSubscriber Plan = "Subscriber"
+
+	// FreeMaxMessageLength is the maximum number of characters in a message sent by a free account
+	FreeMaxMessageLength = 400
+	// SubscriberMaxMessageLength is the maximum number of characters in a message sent by a subscriber
+	SubscriberMaxMessageLength = 600
+)
+
+// MaxMessageLength returns the maximum number of characters in a message sent with this plan, or zero for an unknown plan
+func (p Plan) MaxMessageLength() int {
+	switch p {
+	case PlanFree:
+		return FreeMaxMessageLength
+	case PlanSubscriber:
+		return SubscriberMaxMessageLength
+	default:
+		return 0
+	}
+}
+
+// WithPlan makes the client check the length of messages against the limit of plan before sending them.
+// Messages that are too long are refused locally with MessageLengthLimitExceeded
+func WithPlan(plan Plan) Option {
+	return WithMaxMessageLength(plan.MaxMessageLength())
+}
+
+// WithMaxMessageLength is like WithPlan but with a custom limit, in characters. Zero disables the check
+func WithMaxMessageLength(maxLength int) Option {
+	return func(a *api) {
+		a.maxMessageLength = maxLength
+	}
+}
+
+// Validate returns MessageLengthLimitExceeded if the message has more than maxLength characters.
+// A maxLength of zero or less means there is no limit
+func (b SendMessageBody) Validate(maxLength int) error {
+	return validateMessageLength(b.MessageText, maxLength)
+}
+
+// Validate returns MessageLengthLimitExceeded if the message has more than maxLength characters.
+// A maxLength of zero or less means there is no limit
+func (b SendRoomMessageBody) Validate(maxLength int) error {
+	return validateMessageLength(b.MessageText, maxLength)
+}
+
+func validateMessageLength(text string, maxLength int) error {
+	if maxLength > 0 && utf8.RuneCountInString(text) > maxLength {
+		return MessageLengthLimitExceeded
+	}
+
+	return nil
+}
+
+// SplitMessage cuts text into messages of at most maxLength characters. It splits on sentence boundaries when it
+// can, then on spaces for sentences that are too long, and only cuts through words as a last resort
+func SplitMessage(text string, maxLength int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if maxLength <= 0 || utf8.RuneCountInString(text) <= maxLength {
+		return []string{text}
+	}
+
+	var parts []string
+	var current string
+	for _, piece := range splitPieces(text, maxLength) {
+		switch {
+		case current == "":
+			current = piece
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(piece) <= maxLength:
+			current += " " + piece
+		default:
+			parts = append(parts, current)
+			current = piece
+		}
+	}
+	if current != "" {
+		parts = append(parts, current)
+	}
+
+	return parts
+}
+
+// splitPieces breaks text into sentences, breaking the ones longer than maxLength into words, and the words longer
+// than maxLength into chunks of maxLength characters
+func splitPieces(text string, maxLength int) []string {
+	var pieces []string
+	for _, sentence := range splitSentences(text) {
+		if utf8.RuneCountInString(sentence) <= maxLength {
+			pieces = append(pieces, sentence)
+			continue
+		}
+
+		for _, word := range strings.Fields(sentence) {
+			runes := []rune(word)
+			for len(runes) > maxLength {
+				pieces = append(pieces, string(runes[:maxLength]))
+				runes = runes[maxLength:]
+			}
+			pieces = append(pieces, string(runes))
+		}
+	}
+
+	return pieces
+}
+
+// splitSentences cuts text after every '.', '!', '?' or newline followed by a space
+func splitSentences(text string) []string {
+	var sentences []string
+	runes := []rune(text)
+	start := 0
+	for i, r := range runes {
+		end := r == '\n' || ((r == '.' || r == '!' || r == '?') && i+1 < len(runes) && unicode.IsSpace(runes[i+1]))
+		if !end {
+			continue
+		}
+
+		if sentence := strings.TrimSpace(string(runes[start : i+1])); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = i + 1
+	}
+	if sentence := strings.TrimSpace(string(runes[start:])); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+
+	return sentences
+}
+
+// SendSplitMessage splits text with SplitMessage and sends the parts in order to the Nomi, which replies to each of
+// them. It stops at the first error, returning the responses to the parts sent until then
+func SendSplitMessage(ctx context.Context, client API, nomiID string, text string, maxLength int) ([]SendMessageResponse, error) {
+	var responses []SendMessageResponse
+	for _, part := range SplitMessage(text, maxLength) {
+		res, err := client.SendMessageContext(ctx, nomiID, SendMessageBody{MessageText: part})
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, res)
+	}
+
+	return responses, nil
+}
+
+// SendSplitRoomMessage splits text with SplitMessage and sends the parts in order to the Room. It stops at the first
+// error, returning the responses to the parts sent until then
+func SendSplitRoomMessage(ctx context.Context, client API, roomID string, text string, maxLength int) ([]SendRoomMessageResponse, error) {
+	var responses []SendRoomMessageResponse
+	for _, part := range SplitMessage(text, maxLength) {
+		res, err := client.SendRoomMessageContext(ctx, roomID, SendRoomMessageBody{MessageText: part})
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, res)
+	}
+
+	return responses, nil
+}
@@ -0,0 +1,337 @@
+// Code generated by nomimock/internal/gen from the API interface. DO NOT EDIT.
+
+package nomimock
+
+import (
+	"context"
+	"github.com/vhalmd/nomi-go-sdk"
+)
+
+var _ nomi.API = (*API)(nil)
+
+// API is a programmable implementation of nomi.API. Set the function field of a method to choose what it returns;
+// the Context variant of a method falls back to the function of the plain method, and the other way around.
+// Methods without a function return zero values and ErrNotMocked. Every call is recorded
+type API struct {
+	mocks
+
+	// GetNomisFunc implements GetNomis
+	GetNomisFunc func() (nomi.GetNomisResponse, error)
+
+	// GetNomisContextFunc implements GetNomisContext
+	GetNomisContextFunc func(ctx context.Context) (nomi.GetNomisResponse, error)
+
+	// GetNomiFunc implements GetNomi
+	GetNomiFunc func(nomiID string) (nomi.GetNomiResponse, error)
+
+	// GetNomiContextFunc implements GetNomiContext
+	GetNomiContextFunc func(ctx context.Context, nomiID string) (nomi.GetNomiResponse, error)
+
+	// SendMessageFunc implements SendMessage
+	SendMessageFunc func(nomiID string, body nomi.SendMessageBody) (nomi.SendMessageResponse, error)
+
+	// SendMessageContextFunc implements SendMessageContext
+	SendMessageContextFunc func(ctx context.Context, nomiID string, body nomi.SendMessageBody) (nomi.SendMessageResponse, error)
+
+	// GetRoomsFunc implements GetRooms
+	GetRoomsFunc func() (nomi.GetRoomsResponse, error)
+
+	// GetRoomsContextFunc implements GetRoomsContext
+	GetRoomsContextFunc func(ctx context.Context) (nomi.GetRoomsResponse, error)
+
+	// CreateRoomFunc implements CreateRoom
+	CreateRoomFunc func(body nomi.CreateRoomBody) (nomi.CreateRoomResponse, error)
+
+	// CreateRoomContextFunc implements CreateRoomContext
+	CreateRoomContextFunc func(ctx context.Context, body nomi.CreateRoomBody) (nomi.CreateRoomResponse, error)
+
+	// GetRoomFunc implements GetRoom
+	GetRoomFunc func(roomID string) (nomi.GetRoomResponse, error)
+
+	// GetRoomContextFunc implements GetRoomContext
+	GetRoomContextFunc func(ctx context.Context, roomID string) (nomi.GetRoomResponse, error)
+
+	// SendRoomMessageFunc implements SendRoomMessage
+	SendRoomMessageFunc func(roomID string, body nomi.SendRoomMessageBody) (nomi.SendRoomMessageResponse, error)
+
+	// SendRoomMessageContextFunc implements SendRoomMessageContext
+	SendRoomMessageContextFunc func(ctx context.Context, roomID string, body nomi.SendRoomMessageBody) (nomi.SendRoomMessageResponse, error)
+
+	// RequestNomiRoomMessageFunc implements RequestNomiRoomMessage
+	RequestNomiRoomMessageFunc func(roomID string, body nomi.RequestNomiRoomMessageBody) (nomi.RequestNomiMessageResponse, error)
+
+	// RequestNomiRoomMessageContextFunc implements RequestNomiRoomMessageContext
+	RequestNomiRoomMessageContextFunc func(ctx context.Context, roomID string, body nomi.RequestNomiRoomMessageBody) (nomi.RequestNomiMessageResponse, error)
+
+	// UpdateRoomFunc implements UpdateRoom
+	UpdateRoomFunc func(roomID string, body nomi.UpdateRoomBody) (nomi.UpdateRoomResponse, error)
+
+	// UpdateRoomContextFunc implements UpdateRoomContext
+	UpdateRoomContextFunc func(ctx context.Context, roomID string, body nomi.UpdateRoomBody) (nomi.UpdateRoomResponse, error)
+
+	// DeleteRoomFunc implements DeleteRoom
+	DeleteRoomFunc func(roomID string) (bool, error)
+
+	// DeleteRoomContextFunc implements DeleteRoomContext
+	DeleteRoomContextFunc func(ctx context.Context, roomID string) (bool, error)
+}
+
+// GetNomis records the call and runs GetNomisFunc
+func (m *API) GetNomis() (nomi.GetNomisResponse, error) {
+	m.record("GetNomis", nil)
+	if m.GetNomisFunc != nil {
+		return m.GetNomisFunc()
+	}
+	if m.GetNomisContextFunc != nil {
+		return m.GetNomisContextFunc(context.Background())
+	}
+
+	return nomi.GetNomisResponse{}, ErrNotMocked
+}
+
+// GetNomisContext records the call and runs GetNomisContextFunc
+func (m *API) GetNomisContext(ctx context.Context) (nomi.GetNomisResponse, error) {
+	m.record("GetNomisContext", ctx)
+	if m.GetNomisContextFunc != nil {
+		return m.GetNomisContextFunc(ctx)
+	}
+	if m.GetNomisFunc != nil {
+		return m.GetNomisFunc()
+	}
+
+	return nomi.GetNomisResponse{}, ErrNotMocked
+}
+
+// GetNomi records the call and runs GetNomiFunc
+func (m *API) GetNomi(nomiID string) (nomi.GetNomiResponse, error) {
+	m.record("GetNomi", nil, nomiID)
+	if m.GetNomiFunc != nil {
+		return m.GetNomiFunc(nomiID)
+	}
+	if m.GetNomiContextFunc != nil {
+		return m.GetNomiContextFunc(context.Background(), nomiID)
+	}
+
+	return nomi.GetNomiResponse{}, ErrNotMocked
+}
+
+// GetNomiContext records the call and runs GetNomiContextFunc
+func (m *API) GetNomiContext(ctx context.Context, nomiID string) (nomi.GetNomiResponse, error) {
+	m.record("GetNomiContext", ctx, nomiID)
+	if m.GetNomiContextFunc != nil {
+		return m.GetNomiContextFunc(ctx, nomiID)
+	}
+	if m.GetNomiFunc != nil {
+		return m.GetNomiFunc(nomiID)
+	}
+
+	return nomi.GetNomiResponse{}, ErrNotMocked
+}
+
+// SendMessage records the call and runs SendMessageFunc
+func (m *API) SendMessage(nomiID string, body nomi.SendMessageBody) (nomi.SendMessageResponse, error) {
+	m.record("SendMessage", nil, nomiID, body)
+	if m.SendMessageFunc != nil {
+		return m.SendMessageFunc(nomiID, body)
+	}
+	if m.SendMessageContextFunc != nil {
+		return m.SendMessageContextFunc(context.Background(), nomiID, body)
+	}
+
+	return nomi.SendMessageResponse{}, ErrNotMocked
+}
+
+// SendMessageContext records the call and runs SendMessageContextFunc
+func (m *API) SendMessageContext(ctx context.Context, nomiID string, body nomi.SendMessageBody) (nomi.SendMessageResponse, error) {
+	m.record("SendMessageContext", ctx, nomiID, body)
+	if m.SendMessageContextFunc != nil {
+		return m.SendMessageContextFunc(ctx, nomiID, body)
+	}
+	if m.SendMessageFunc != nil {
+		return m.SendMessageFunc(nomiID, body)
+	}
+
+	return nomi.SendMessageResponse{}, ErrNotMocked
+}
+
+// GetRooms records the call and runs GetRoomsFunc
+func (m *API) GetRooms() (nomi.GetRoomsResponse, error) {
+	m.record("GetRooms", nil)
+	if m.GetRoomsFunc != nil {
+		return m.GetRoomsFunc()
+	}
+	if m.GetRoomsContextFunc != nil {
+		return m.GetRoomsContextFunc(context.Background())
+	}
+
+	return nomi.GetRoomsResponse{}, ErrNotMocked
+}
+
+// GetRoomsContext records the call and runs GetRoomsContextFunc
+func (m *API) GetRoomsContext(ctx context.Context) (nomi.GetRoomsResponse, error) {
+	m.record("GetRoomsContext", ctx)
+	if m.GetRoomsContextFunc != nil {
+		return m.GetRoomsContextFunc(ctx)
+	}
+	if m.GetRoomsFunc != nil {
+		return m.GetRoomsFunc()
+	}
+
+	return nomi.GetRoomsResponse{}, ErrNotMocked
+}
+
+// CreateRoom records the call and runs CreateRoomFunc
+func (m *API) CreateRoom(body nomi.CreateRoomBody) (nomi.CreateRoomResponse, error) {
+	m.record("CreateRoom", nil, body)
+	if m.CreateRoomFunc != nil {
+		return m.CreateRoomFunc(body)
+	}
+	if m.CreateRoomContextFunc != nil {
+		return m.CreateRoomContextFunc(context.Background(), body)
+	}
+
+	return nomi.CreateRoomResponse{}, ErrNotMocked
+}
+
+// CreateRoomContext records the call and runs CreateRoomContextFunc
+func (m *API) CreateRoomContext(ctx context.Context, body nomi.CreateRoomBody) (nomi.CreateRoomResponse, error) {
+	m.record("CreateRoomContext", ctx, body)
+	if m.CreateRoomContextFunc != nil {
+		return m.CreateRoomContextFunc(ctx, body)
+	}
+	if m.CreateRoomFunc != nil {
+		return m.CreateRoomFunc(body)
+	}
+
+	return nomi.CreateRoomResponse{}, ErrNotMocked
+}
+
+// GetRoom records the call and runs GetRoomFunc
+func (m *API) GetRoom(roomID string) (nomi.GetRoomResponse, error) {
+	m.record("GetRoom", nil, roomID)
+	if m.GetRoomFunc != nil {
+		return m.GetRoomFunc(roomID)
+	}
+	if m.GetRoomContextFunc != nil {
+		return m.GetRoomContextFunc(context.Background(), roomID)
+	}
+
+	return nomi.GetRoomResponse{}, ErrNotMocked
+}
+
+// GetRoomContext records the call and runs GetRoomContextFunc
+func (m *API) GetRoomContext(ctx context.Context, roomID string) (nomi.GetRoomResponse, error) {
+	m.record("GetRoomContext", ctx, roomID)
+	if m.GetRoomContextFunc != nil {
+		return m.GetRoomContextFunc(ctx, roomID)
+	}
+	if m.GetRoomFunc != nil {
+		return m.GetRoomFunc(roomID)
+	}
+
+	return nomi.GetRoomResponse{}, ErrNotMocked
+}
+
+// SendRoomMessage records the call and runs SendRoomMessageFunc
+func (m *API) SendRoomMessage(roomID string, body nomi.SendRoomMessageBody) (nomi.SendRoomMessageResponse, error) {
+	m.record("SendRoomMessage", nil, roomID, body)
+	if m.SendRoomMessageFunc != nil {
+		return m.SendRoomMessageFunc(roomID, body)
+	}
+	if m.SendRoomMessageContextFunc != nil {
+		return m.SendRoomMessageContextFunc(context.Background(), roomID, body)
+	}
+
+	return nomi.SendRoomMessageResponse{}, ErrNotMocked
+}
+
+// SendRoomMessageContext records the call and runs SendRoomMessageContextFunc
+func (m *API) SendRoomMessageContext(ctx context.Context, roomID string, body nomi.SendRoomMessageBody) (nomi.SendRoomMessageResponse, error) {
+	m.record("SendRoomMessageContext", ctx, roomID, body)
+	if m.SendRoomMessageContextFunc != nil {
+		return m.SendRoomMessageContextFunc(ctx, roomID, body)
+	}
+	if m.SendRoomMessageFunc != nil {
+		return m.SendRoomMessageFunc(roomID, body)
+	}
+
+	return nomi.SendRoomMessageResponse{}, ErrNotMocked
+}
+
+// RequestNomiRoomMessage records the call and runs RequestNomiRoomMessageFunc
+func (m *API) RequestNomiRoomMessage(roomID string, body nomi.RequestNomiRoomMessageBody) (nomi.RequestNomiMessageResponse, error) {
+	m.record("RequestNomiRoomMessage", nil, roomID, body)
+	if m.RequestNomiRoomMessageFunc != nil {
+		return m.RequestNomiRoomMessageFunc(roomID, body)
+	}
+	if m.RequestNomiRoomMessageContextFunc != nil {
+		return m.RequestNomiRoomMessageContextFunc(context.Background(), roomID, body)
+	}
+
+	return nomi.RequestNomiMessageResponse{}, ErrNotMocked
+}
+
+// RequestNomiRoomMessageContext records the call and runs RequestNomiRoomMessageContextFunc
+func (m *API) RequestNomiRoomMessageContext(ctx context.Context, roomID string, body nomi.RequestNomiRoomMessageBody) (nomi.RequestNomiMessageResponse, error) {
+	m.record("RequestNomiRoomMessageContext", ctx, roomID, body)
+	if m.RequestNomiRoomMessageContextFunc != nil {
+		return m.RequestNomiRoomMessageContextFunc(ctx, roomID, body)
+	}
+	if m.RequestNomiRoomMessageFunc != nil {
+		return m.RequestNomiRoomMessageFunc(roomID, body)
+	}
+
+	return nomi.RequestNomiMessageResponse{}, ErrNotMocked
+}
+
+// UpdateRoom records the call and runs UpdateRoomFunc
+func (m *API) UpdateRoom(roomID string, body nomi.UpdateRoomBody) (nomi.UpdateRoomResponse, error) {
+	m.record("UpdateRoom", nil, roomID, body)
+	if m.UpdateRoomFunc != nil {
+		return m.UpdateRoomFunc(roomID, body)
+	}
+	if m.UpdateRoomContextFunc != nil {
+		return m.UpdateRoomContextFunc(context.Background(), roomID, body)
+	}
+
+	return nomi.UpdateRoomResponse{}, ErrNotMocked
+}
+
+// UpdateRoomContext records the call and runs UpdateRoomContextFunc
+func (m *API) UpdateRoomContext(ctx context.Context, roomID string, body nomi.UpdateRoomBody) (nomi.UpdateRoomResponse, error) {
+	m.record("UpdateRoomContext", ctx, roomID, body)
+	if m.UpdateRoomContextFunc != nil {
+		return m.UpdateRoomContextFunc(ctx, roomID, body)
+	}
+	if m.UpdateRoomFunc != nil {
+		return m.UpdateRoomFunc(roomID, body)
+	}
+
+	return nomi.UpdateRoomResponse{}, ErrNotMocked
+}
+
+// DeleteRoom records the call and runs DeleteRoomFunc
+func (m *API) DeleteRoom(roomID string) (bool, error) {
+	m.record("DeleteRoom", nil, roomID)
+	if m.DeleteRoomFunc != nil {
+		return m.DeleteRoomFunc(roomID)
+	}
+	if m.DeleteRoomContextFunc != nil {
+		return m.DeleteRoomContextFunc(context.Background(), roomID)
+	}
+
+	return false, ErrNotMocked
+}
+
+// DeleteRoomContext records the call and runs DeleteRoomContextFunc
+func (m *API) DeleteRoomContext(ctx context.Context, roomID string) (bool, error) {
+	m.record("DeleteRoomContext", ctx, roomID)
+	if m.DeleteRoomContextFunc != nil {
+		return m.DeleteRoomContextFunc(ctx, roomID)
+	}
+	if m.DeleteRoomFunc != nil {
+		return m.DeleteRoomFunc(roomID)
+	}
+
+	return false, ErrNotMocked
+}
@@ -0,0 +1,230 @@
+// Command gen writes nomimock/api.go from the API interface declared in base.go. Run it with go generate
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"strings"
+)
+
+type param struct {
+	name string
+	typ  string
+}
+
+type method struct {
+	name    string
+	params  []param
+	results []string
+}
+
+func main() {
+	source := "../base.go"
+	output := "api.go"
+	if len(os.Args) == 3 {
+		source, output = os.Args[1], os.Args[2]
+	}
+
+	methods, err := parseInterface(source, "API")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code, err := generate(methods)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = os.WriteFile(output, code, 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseInterface reads the methods of the interface called name in the Go file at path
+func parseInterface(path string, name string) ([]method, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var iface *ast.InterfaceType
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if ok && spec.Name.Name == name {
+			iface, _ = spec.Type.(*ast.InterfaceType)
+		}
+		return iface == nil
+	})
+	if iface == nil {
+		return nil, fmt.Errorf("interface %s not found in %s", name, path)
+	}
+
+	var methods []method
+	for _, field := range iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return nil, fmt.Errorf("unsupported embedded interface in %s", name)
+		}
+
+		m := method{name: field.Names[0].Name}
+		for i, p := range fn.Params.List {
+			typ := qualify(p.Type)
+			if len(p.Names) == 0 {
+				m.params = append(m.params, param{name: fmt.Sprintf("arg%d", i), typ: typ})
+			}
+			for _, n := range p.Names {
+				m.params = append(m.params, param{name: n.Name, typ: typ})
+			}
+		}
+		for _, r := range fn.Results.List {
+			count := max(len(r.Names), 1)
+			for range count {
+				m.results = append(m.results, qualify(r.Type))
+			}
+		}
+		methods = append(methods, m)
+	}
+
+	return methods, nil
+}
+
+// qualify prints a type expression of package nomi as seen from another package
+func qualify(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if ast.IsExported(t.Name) {
+			return "nomi." + t.Name
+		}
+		return t.Name
+	case *ast.SelectorExpr:
+		return qualify(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + qualify(t.X)
+	case *ast.ArrayType:
+		return "[]" + qualify(t.Elt)
+	case *ast.MapType:
+		return "map[" + qualify(t.Key) + "]" + qualify(t.Value)
+	default:
+		log.Fatalf("unsupported type %T", expr)
+		return ""
+	}
+}
+
+func generate(methods []method) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(`// Code generated by nomimock/internal/gen from the API interface. DO NOT EDIT.
+
+package nomimock
+
+import (
+	"context"
+	"github.com/vhalmd/nomi-go-sdk"
+)
+
+var _ nomi.API = (*API)(nil)
+
+// API is a programmable implementation of nomi.API. Set the function field of a method to choose what it returns;
+// the Context variant of a method falls back to the function of the plain method, and the other way around.
+// Methods without a function return zero values and ErrNotMocked. Every call is recorded
+type API struct {
+	mocks
+`)
+	for _, m := range methods {
+		fmt.Fprintf(&b, "\n\t// %sFunc implements %s\n", m.name, m.name)
+		fmt.Fprintf(&b, "\t%sFunc func(%s) %s\n", m.name, paramList(m.params), resultList(m.results))
+	}
+	b.WriteString("}\n")
+
+	byName := map[string]method{}
+	for _, m := range methods {
+		byName[m.name] = m
+	}
+
+	for _, m := range methods {
+		var args []string
+		for _, p := range m.params {
+			if p.typ != "context.Context" {
+				args = append(args, p.name)
+			}
+		}
+
+		fmt.Fprintf(&b, "\n// %s records the call and runs %sFunc\n", m.name, m.name)
+		fmt.Fprintf(&b, "func (m *API) %s(%s) %s {\n", m.name, paramList(m.params), resultList(m.results))
+		fmt.Fprintf(&b, "\tm.record(%q, %s)\n", m.name, strings.Join(append([]string{ctxArg(m)}, args...), ", "))
+		fmt.Fprintf(&b, "\tif m.%sFunc != nil {\n\t\treturn m.%sFunc(%s)\n\t}\n", m.name, m.name, callArgs(m.params, false))
+
+		if base, ok := strings.CutSuffix(m.name, "Context"); ok && byName[base].name != "" {
+			fmt.Fprintf(&b, "\tif m.%sFunc != nil {\n\t\treturn m.%sFunc(%s)\n\t}\n", base, base, callArgs(byName[base].params, false))
+		} else if ctxVariant, ok := byName[m.name+"Context"]; ok {
+			fmt.Fprintf(&b, "\tif m.%sFunc != nil {\n\t\treturn m.%sFunc(%s)\n\t}\n", ctxVariant.name, ctxVariant.name, callArgs(ctxVariant.params, true))
+		}
+
+		b.WriteString("\n" + zeroReturn(m.results) + "}\n")
+	}
+
+	return format.Source(b.Bytes())
+}
+
+func paramList(params []param) string {
+	var parts []string
+	for _, p := range params {
+		parts = append(parts, p.name+" "+p.typ)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func resultList(results []string) string {
+	if len(results) == 1 {
+		return results[0]
+	}
+	return "(" + strings.Join(results, ", ") + ")"
+}
+
+// ctxArg returns the expression recorded as the context of a call to m
+func ctxArg(m method) string {
+	for _, p := range m.params {
+		if p.typ == "context.Context" {
+			return p.name
+		}
+	}
+	return "nil"
+}
+
+// callArgs returns the arguments to pass to a function taking params, from the parameters of the same name in scope.
+// When background is set, context parameters are filled with context.Background() instead
+func callArgs(params []param, background bool) string {
+	var args []string
+	for _, p := range params {
+		if p.typ == "context.Context" && background {
+			args = append(args, "context.Background()")
+			continue
+		}
+		args = append(args, p.name)
+	}
+	return strings.Join(args, ", ")
+}
+
+func zeroReturn(results []string) string {
+	var values []string
+	for _, r := range results {
+		switch {
+		case r == "error":
+			values = append(values, "ErrNotMocked")
+		case r == "bool":
+			values = append(values, "false")
+		case strings.HasPrefix(r, "nomi."):
+			values = append(values, r+"{}")
+		default:
+			log.Fatalf("no zero value for %s", r)
+		}
+	}
+	return "\treturn " + strings.Join(values, ", ") + "\n"
+}
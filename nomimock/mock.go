@@ -0,0 +1,127 @@
+// Package nomimock provides a programmable mock of nomi.API, to unit test code depending on the SDK without any
+// HTTP server:
+//
+//	mock := &nomimock.API{
+//		SendMessageFunc: func(nomiID string, body nomi.SendMessageBody) (nomi.SendMessageResponse, error) {
+//			return nomi.SendMessageResponse{ReplyMessage: nomi.Message{Text: "Hi!"}}, nil
+//		},
+//	}
+//	mock.Expect("SendMessage", 1)
+//
+//	bot := NewBot(mock)
+//	bot.Greet(nomiID)
+//
+//	mock.AssertExpectations(t)
+//
+// The API type is generated from the interface, run go generate after changing it.
+package nomimock
+
+//go:generate go run ./internal/gen ../base.go api.go
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNotMocked is returned by the methods of API whose function field is not set
+var ErrNotMocked = errors.New("nomimock: method called without a mock function")
+
+// Call is a recorded call to a method of API
+type Call struct {
+	// Method is the name of the method called, e.g. "SendMessageContext"
+	Method string
+	// Ctx is the context given to the Context variants of the methods, nil for the others
+	Ctx context.Context
+	// Args are the other arguments, in order
+	Args []any
+}
+
+// TB is the part of testing.TB used by AssertExpectations
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// mocks holds the state shared by the methods of API
+type mocks struct {
+	mu           sync.Mutex
+	calls        []Call
+	expectations map[string]int
+}
+
+func (m *mocks) record(method string, ctx context.Context, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, Call{Method: method, Ctx: ctx, Args: args})
+}
+
+// Calls returns the calls made so far, in order
+func (m *mocks) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Call(nil), m.calls...)
+}
+
+// CallsTo returns the calls made to method so far, in order. The name of a method without context, e.g.
+// "SendMessage", also counts the calls to its Context variant, so that it does not matter which one the code under
+// test uses; "SendMessageContext" only counts the calls to SendMessageContext
+func (m *mocks) CallsTo(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var calls []Call
+	for _, call := range m.calls {
+		if call.Method == method || call.Method == method+"Context" {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// CallCount returns how many times method was called, counted as by CallsTo
+func (m *mocks) CallCount(method string) int {
+	return len(m.CallsTo(method))
+}
+
+// Expect records that method should be called exactly times times, which AssertExpectations verifies. As with
+// CallsTo, Expect("SendMessage", 1) is met by a call to SendMessage or to SendMessageContext
+func (m *mocks) Expect(method string, times int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.expectations == nil {
+		m.expectations = map[string]int{}
+	}
+	m.expectations[method] = times
+}
+
+// AssertExpectations reports an error on t for every method not called the number of times given to Expect
+func (m *mocks) AssertExpectations(t TB) {
+	t.Helper()
+
+	m.mu.Lock()
+	expectations := make(map[string]int, len(m.expectations))
+	for method, times := range m.expectations {
+		expectations[method] = times
+	}
+	m.mu.Unlock()
+
+	for method, times := range expectations {
+		if count := m.CallCount(method); count != times {
+			t.Errorf("nomimock: expected %s to be called %d times, got %d", method, times, count)
+		}
+	}
+}
+
+// Reset forgets the calls and expectations recorded so far. The function fields are kept
+func (m *mocks) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = nil
+	m.expectations = nil
+}
@@ -0,0 +1,174 @@
+// Package nomiotel instruments the Nomi client with OpenTelemetry. NewInterceptor returns an interceptor to give to
+// nomi.WithInterceptors, which starts a span named after the API method for each call and records metrics:
+//
+//	interceptor, err := nomiotel.NewInterceptor()
+//	if err != nil {
+//		return err
+//	}
+//	client := nomi.NewClient(apiKey, nomi.WithInterceptors(interceptor))
+//
+// It uses the global tracer and meter providers unless others are given with WithTracerProvider and
+// WithMeterProvider. The metrics are:
+//
+//   - nomi.client.duration, a histogram of the duration of the calls in seconds, retries included
+//   - nomi.client.errors, the number of failed calls by error type
+//   - nomi.client.messages, the number of messages sent with SendMessage and SendRoomMessage
+//
+// All of them have the nomi.operation attribute, and the failed calls the error.type attribute set to
+// nomi.ErrorType of the error.
+package nomiotel
+
+import (
+	"context"
+	"github.com/vhalmd/nomi-go-sdk"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"unicode/utf8"
+)
+
+// ScopeName is the instrumentation scope of the tracer and the meter
+const ScopeName = "github.com/vhalmd/nomi-go-sdk/nomiotel"
+
+// Attribute keys set on the spans and the metrics
+const (
+	OperationKey     = attribute.Key("nomi.operation")
+	NomiUUIDKey      = attribute.Key("nomi.nomi.uuid")
+	RoomUUIDKey      = attribute.Key("nomi.room.uuid")
+	MessageLengthKey = attribute.Key("nomi.message.length")
+	RetriesKey       = attribute.Key("nomi.retries")
+	MethodKey        = attribute.Key("http.request.method")
+	StatusCodeKey    = attribute.Key("http.response.status_code")
+	ErrorTypeKey     = attribute.Key("error.type")
+)
+
+// Option configures NewInterceptor
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider makes the interceptor create its spans with provider instead of the global one
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider makes the interceptor record its metrics with provider instead of the global one
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+type instruments struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+	messages metric.Int64Counter
+}
+
+// spanKey keeps the span of a call in its context, for the hooks ending it
+type spanKey struct{}
+
+// NewInterceptor creates an interceptor tracing the calls and recording their metrics. It fails if the instruments
+// cannot be created
+func NewInterceptor(opts ...Option) (nomi.Interceptor, error) {
+	c := config{tracerProvider: otel.GetTracerProvider(), meterProvider: otel.GetMeterProvider()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	meter := c.meterProvider.Meter(ScopeName)
+	i := instruments{tracer: c.tracerProvider.Tracer(ScopeName)}
+	var err error
+	i.duration, err = meter.Float64Histogram("nomi.client.duration",
+		metric.WithUnit("s"), metric.WithDescription("Duration of the calls to the Nomi API, retries included"))
+	if err != nil {
+		return nomi.Interceptor{}, err
+	}
+	i.errors, err = meter.Int64Counter("nomi.client.errors",
+		metric.WithUnit("{error}"), metric.WithDescription("Number of failed calls to the Nomi API"))
+	if err != nil {
+		return nomi.Interceptor{}, err
+	}
+	i.messages, err = meter.Int64Counter("nomi.client.messages",
+		metric.WithUnit("{message}"), metric.WithDescription("Number of messages sent to Nomis and Rooms"))
+	if err != nil {
+		return nomi.Interceptor{}, err
+	}
+
+	return nomi.Interceptor{BeforeSend: i.start, AfterReceive: i.succeeded, OnError: i.failed}, nil
+}
+
+func (i instruments) start(ctx context.Context, call *nomi.Call) (context.Context, error) {
+	attrs := []attribute.KeyValue{OperationKey.String(call.Operation), MethodKey.String(call.Method)}
+	if call.NomiID != "" {
+		attrs = append(attrs, NomiUUIDKey.String(call.NomiID))
+	}
+	if call.RoomID != "" {
+		attrs = append(attrs, RoomUUIDKey.String(call.RoomID))
+	}
+	if text, ok := messageText(call.Body); ok {
+		attrs = append(attrs, MessageLengthKey.Int(utf8.RuneCountInString(text)))
+	}
+
+	ctx, span := i.tracer.Start(ctx, call.Operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+
+	return context.WithValue(ctx, spanKey{}, span), nil
+}
+
+func (i instruments) succeeded(ctx context.Context, call *nomi.Call) {
+	i.end(ctx, call, nil)
+
+	if _, ok := messageText(call.Body); ok {
+		i.messages.Add(ctx, 1, metric.WithAttributes(OperationKey.String(call.Operation)))
+	}
+}
+
+func (i instruments) failed(ctx context.Context, call *nomi.Call, err error) {
+	i.end(ctx, call, err)
+
+	i.errors.Add(ctx, 1, metric.WithAttributes(OperationKey.String(call.Operation), ErrorTypeKey.String(nomi.ErrorType(err))))
+}
+
+// end ends the span of the call and records its duration
+func (i instruments) end(ctx context.Context, call *nomi.Call, err error) {
+	attrs := []attribute.KeyValue{OperationKey.String(call.Operation)}
+	if err != nil {
+		attrs = append(attrs, ErrorTypeKey.String(nomi.ErrorType(err)))
+	}
+	i.duration.Record(ctx, call.Duration.Seconds(), metric.WithAttributes(attrs...))
+
+	span, ok := ctx.Value(spanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(RetriesKey.Int(max(call.Attempts-1, 0)))
+	if call.StatusCode != 0 {
+		span.SetAttributes(StatusCodeKey.Int(call.StatusCode))
+	}
+	if err != nil {
+		span.SetAttributes(ErrorTypeKey.String(nomi.ErrorType(err)))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// messageText returns the text of the message sent by a call with body, if it sends one
+func messageText(body any) (string, bool) {
+	switch b := body.(type) {
+	case nomi.SendMessageBody:
+		return b.MessageText, true
+	case nomi.SendRoomMessageBody:
+		return b.MessageText, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,177 @@
+// Package nomiprom exposes the usage of the Nomi client as Prometheus metrics. A Collector counts the calls made
+// through its interceptor and is registered like any other collector:
+//
+//	collector := nomiprom.NewCollector()
+//	client := nomi.NewClient(apiKey, nomi.WithInterceptors(collector.Interceptor()), nomi.WithDailyQuota(100))
+//	collector.TrackQuota(client)
+//	prometheus.MustRegister(collector)
+//
+// The metrics are, with the default namespace:
+//
+//   - nomi_client_calls_total, the number of calls by operation
+//   - nomi_client_errors_total, the number of failed calls by operation and error type, e.g. "NoReply"
+//   - nomi_client_request_duration_seconds, a histogram of the duration of the calls by operation, retries included
+//   - nomi_client_in_flight_requests, the number of calls in progress by operation
+//   - nomi_client_quota_remaining_messages, the messages left in the daily quota of the tracked client, if it has one
+//
+// The error type is nomi.ErrorType of the error.
+package nomiprom
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vhalmd/nomi-go-sdk"
+	"sync"
+)
+
+// DefaultNamespace prefixes the names of the metrics unless WithNamespace is given
+const DefaultNamespace = "nomi"
+
+// Option configures NewCollector
+type Option func(*config)
+
+type config struct {
+	namespace   string
+	buckets     []float64
+	constLabels prometheus.Labels
+}
+
+// WithNamespace prefixes the names of the metrics with namespace instead of DefaultNamespace
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithBuckets sets the buckets of the duration histogram, in seconds. Defaults to prometheus.DefBuckets
+func WithBuckets(buckets []float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// WithConstLabels adds labels with fixed values to all the metrics, e.g. to tell several clients apart
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(c *config) {
+		c.constLabels = labels
+	}
+}
+
+// Collector is a prometheus.Collector of the metrics of the calls made through its interceptor. It is safe for
+// concurrent use
+type Collector struct {
+	calls    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	quota    *prometheus.Desc
+
+	mu     sync.Mutex
+	client nomi.API
+}
+
+// NewCollector creates a Collector, to register on a prometheus.Registerer
+func NewCollector(opts ...Option) *Collector {
+	c := config{namespace: DefaultNamespace, buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &Collector{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "client",
+			Name:        "calls_total",
+			Help:        "Number of calls to the Nomi API.",
+			ConstLabels: c.constLabels,
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "client",
+			Name:        "errors_total",
+			Help:        "Number of failed calls to the Nomi API, by error type.",
+			ConstLabels: c.constLabels,
+		}, []string{"operation", "error_type"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "client",
+			Name:        "request_duration_seconds",
+			Help:        "Duration of the calls to the Nomi API, retries included.",
+			Buckets:     c.buckets,
+			ConstLabels: c.constLabels,
+		}, []string{"operation"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   c.namespace,
+			Subsystem:   "client",
+			Name:        "in_flight_requests",
+			Help:        "Number of calls to the Nomi API in progress.",
+			ConstLabels: c.constLabels,
+		}, []string{"operation"}),
+		quota: prometheus.NewDesc(
+			prometheus.BuildFQName(c.namespace, "client", "quota_remaining_messages"),
+			"Number of messages left in the daily quota of the client.",
+			nil, c.constLabels,
+		),
+	}
+}
+
+// Interceptor returns the interceptor to give to nomi.WithInterceptors for the collector to see the calls
+func (c *Collector) Interceptor() nomi.Interceptor {
+	return nomi.Interceptor{
+		BeforeSend: func(ctx context.Context, call *nomi.Call) (context.Context, error) {
+			c.inFlight.WithLabelValues(call.Operation).Inc()
+			return nil, nil
+		},
+		AfterReceive: func(ctx context.Context, call *nomi.Call) {
+			c.observe(call, nil)
+		},
+		OnError: func(ctx context.Context, call *nomi.Call, err error) {
+			c.observe(call, err)
+		},
+	}
+}
+
+func (c *Collector) observe(call *nomi.Call, err error) {
+	c.inFlight.WithLabelValues(call.Operation).Dec()
+	c.calls.WithLabelValues(call.Operation).Inc()
+	c.duration.WithLabelValues(call.Operation).Observe(call.Duration.Seconds())
+	if err != nil {
+		c.errors.WithLabelValues(call.Operation, nomi.ErrorType(err)).Inc()
+	}
+}
+
+// TrackQuota makes the collector report the messages left in the daily quota of client, set with
+// nomi.WithDailyQuota. Nothing is reported for a client without a quota or that does not report it, see nomi.QuotaOf
+func (c *Collector) TrackQuota(client nomi.API) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.client = client
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.calls.Describe(ch)
+	c.errors.Describe(ch)
+	c.duration.Describe(ch)
+	c.inFlight.Describe(ch)
+	ch <- c.quota
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.calls.Collect(ch)
+	c.errors.Collect(ch)
+	c.duration.Collect(ch)
+	c.inFlight.Collect(ch)
+
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
+		return
+	}
+	if stats, ok := nomi.QuotaOf(client); ok && stats.Limit > 0 {
+		ch <- prometheus.MustNewConstMetric(c.quota, prometheus.GaugeValue, float64(stats.Remaining))
+	}
+}
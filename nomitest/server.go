@@ -0,0 +1,678 @@
+// Package nomitest provides an in-process fake of the Nomi API, to test code using the SDK without network access.
+//
+// The fake keeps its Nomis, Rooms and messages in memory, answers with scriptable replies, and can be told to fail
+// any operation with any of the error types of the Nomi API:
+//
+//	server := nomitest.NewServer()
+//	defer server.Close()
+//
+//	bob := server.AddNomi("Bob", nomi.MALE, nomi.FRIEND)
+//	server.SetReplyFunc(func(n nomi.Nomi, text string) string { return "Hi!" })
+//	server.FailNext("SendMessage", "NomiStillResponding")
+//
+//	client := server.Client()
+package nomitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ReplyFunc computes what n answers to text. In Rooms, text is the last message sent in the Room, if any
+type ReplyFunc func(n nomi.Nomi, text string) string
+
+// Server is a fake Nomi API. Its exported fields can be changed before the first request
+type Server struct {
+	*httptest.Server
+
+	// APIKey is the key clients must send in the Authorization header. Any key is accepted when it is empty
+	APIKey string
+	// MaxMessageLength is the maximum number of characters in a message, 600 by default like for subscribers
+	MaxMessageLength int
+	// MaxRooms is the maximum number of Rooms, 10 by default
+	MaxRooms int
+
+	mu       sync.Mutex
+	nomis    []*nomi.Nomi
+	rooms    []*nomi.Room
+	messages map[uuid.UUID][]nomi.Message
+	reply    ReplyFunc
+	scripted map[uuid.UUID][]string
+	failNext map[string][]string
+	failures map[string]string
+	calls    map[string]int
+}
+
+// NewServer starts a fake Nomi API without any Nomi or Room. Close it when done
+func NewServer() *Server {
+	s := &Server{
+		MaxMessageLength: nomi.SubscriberMaxMessageLength,
+		MaxRooms:         10,
+		messages:         map[uuid.UUID][]nomi.Message{},
+		scripted:         map[uuid.UUID][]string{},
+		failNext:         map[string][]string{},
+		failures:         map[string]string{},
+		calls:            map[string]int{},
+		reply:            defaultReply,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/nomis", s.handle("GetNomis", s.getNomis))
+	mux.HandleFunc("GET /v1/nomis/{id}", s.handle("GetNomi", s.getNomi))
+	mux.HandleFunc("POST /v1/nomis/{id}/chat", s.handle("SendMessage", s.sendMessage))
+	mux.HandleFunc("GET /v1/rooms", s.handle("GetRooms", s.getRooms))
+	mux.HandleFunc("POST /v1/rooms", s.handle("CreateRoom", s.createRoom))
+	mux.HandleFunc("GET /v1/rooms/{id}", s.handle("GetRoom", s.getRoom))
+	mux.HandleFunc("PUT /v1/rooms/{id}", s.handle("UpdateRoom", s.updateRoom))
+	mux.HandleFunc("DELETE /v1/rooms/{id}", s.handle("DeleteRoom", s.deleteRoom))
+	mux.HandleFunc("POST /v1/rooms/{id}/chat", s.handle("SendRoomMessage", s.sendRoomMessage))
+	mux.HandleFunc("POST /v1/rooms/{id}/chat/request", s.handle("RequestNomiRoomMessage", s.requestNomiRoomMessage))
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+func defaultReply(n nomi.Nomi, text string) string {
+	if text == "" {
+		return fmt.Sprintf("Hi, this is %s!", n.Name)
+	}
+
+	return fmt.Sprintf("%s heard: %s", n.Name, text)
+}
+
+// BaseURL is the address to give to nomi.WithBaseURL to use the server
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1/"
+}
+
+// Client creates a client for the server, with the options given applied after the base URL
+func (s *Server) Client(opts ...nomi.Option) nomi.API {
+	apiKey := s.APIKey
+	if apiKey == "" {
+		apiKey = "nomitest"
+	}
+
+	return nomi.NewClient(apiKey, append([]nomi.Option{nomi.WithBaseURL(s.BaseURL())}, opts...)...)
+}
+
+// AddNomi creates a Nomi associated with the account
+func (s *Server) AddNomi(name string, gender nomi.Gender, relationship nomi.RelationshipType) nomi.Nomi {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := &nomi.Nomi{
+		UUID:             uuid.New(),
+		Gender:           gender,
+		Name:             name,
+		Created:          time.Now().UTC(),
+		RelationshipType: relationship,
+	}
+	s.nomis = append(s.nomis, n)
+
+	return *n
+}
+
+// AddRoom creates a Room directly, bypassing the validation of CreateRoom. The Nomis must have been added already
+func (s *Server) AddRoom(name string, status nomi.RoomStatus, nomiIDs ...uuid.UUID) nomi.Room {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	room := &nomi.Room{
+		UUID:    uuid.New(),
+		Name:    name,
+		Created: now,
+		Updated: now,
+		Status:  status,
+		Nomis:   []nomi.Nomi{},
+	}
+	for _, id := range nomiIDs {
+		if n := s.findNomi(id); n != nil {
+			room.Nomis = append(room.Nomis, *n)
+		}
+	}
+	s.rooms = append(s.rooms, room)
+
+	return *room
+}
+
+// SetRoomStatus changes the status of a Room, e.g. to simulate it being created
+func (s *Server) SetRoomStatus(roomID uuid.UUID, status nomi.RoomStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if room := s.findRoom(roomID); room != nil {
+		room.Status = status
+		room.Updated = time.Now().UTC()
+	}
+}
+
+// Rooms returns the Rooms currently on the server
+func (s *Server) Rooms() []nomi.Room {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rooms := make([]nomi.Room, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		rooms = append(rooms, *room)
+	}
+
+	return rooms
+}
+
+// Messages returns the messages exchanged in the main chat of a Nomi or in a Room, oldest first
+func (s *Server) Messages(id uuid.UUID) []nomi.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]nomi.Message(nil), s.messages[id]...)
+}
+
+// SetReplyFunc changes how Nomis reply to messages
+func (s *Server) SetReplyFunc(reply ReplyFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reply = reply
+}
+
+// Script queues the next replies of a Nomi. They are used in order, before falling back to the ReplyFunc
+func (s *Server) Script(nomiID uuid.UUID, replies ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scripted[nomiID] = append(s.scripted[nomiID], replies...)
+}
+
+// FailNext makes the next call to operation, named after the API method (e.g. "SendMessage"), fail with errorType.
+// Calling it several times queues several failures
+func (s *Server) FailNext(operation string, errorType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failNext[operation] = append(s.failNext[operation], errorType)
+}
+
+// Fail makes every call to operation fail with errorType until ClearFailures is called
+func (s *Server) Fail(operation string, errorType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[operation] = errorType
+}
+
+// ClearFailures removes the failures set with Fail and FailNext
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failNext = map[string][]string{}
+	s.failures = map[string]string{}
+}
+
+// Calls returns how many requests were made for operation, failed ones included
+func (s *Server) Calls(operation string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[operation]
+}
+
+// StatusCode returns the HTTP status code the server uses for errorType
+func StatusCode(errorType string) int {
+	switch errorType {
+	case "NomiNotFound", "RoomNotFound", "RoomNomiNotFound":
+		return http.StatusNotFound
+	case "NomiStillResponding", "NomiNotReady", "OngoingVoiceCallDetected", "RoomStillCreating", "RoomNomiNotReadyForMessage":
+		return http.StatusConflict
+	case "InsufficientPlan", "ExceededRoomLimit", "LimitExceeded":
+		return http.StatusForbidden
+	case "InvalidContentType":
+		return http.StatusUnsupportedMediaType
+	case "NoReply":
+		return http.StatusInternalServerError
+	case "Unauthorized":
+		return http.StatusUnauthorized
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// apiError is returned by handlers to answer with an error of the Nomi API
+type apiError struct {
+	Type   string                `json:"type"`
+	Issues []nomi.APIErrorIssues `json:"issues,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	return e.Type
+}
+
+func fail(errorType string) error {
+	return &apiError{Type: errorType}
+}
+
+// handle wraps the handler of operation with authentication, failure injection and error responses
+func (s *Server) handle(operation string, handler handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := s.check(operation, r)
+		if err == nil {
+			err = handler(w, r)
+		}
+		if err == nil {
+			return
+		}
+
+		e, ok := err.(*apiError)
+		if !ok {
+			e = &apiError{Type: "InternalServerError"}
+		}
+		writeJSON(w, StatusCode(e.Type), map[string]any{"error": e})
+	}
+}
+
+// check counts the call and returns the error it should fail with before reaching the handler, if any
+func (s *Server) check(operation string, r *http.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[operation]++
+
+	if s.APIKey != "" && r.Header.Get("Authorization") != s.APIKey {
+		return fail("Unauthorized")
+	}
+
+	if queued := s.failNext[operation]; len(queued) > 0 {
+		s.failNext[operation] = queued[1:]
+		return fail(queued[0])
+	}
+	if errorType, ok := s.failures[operation]; ok {
+		return fail(errorType)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// decodeBody reads the JSON body of a request, failing like the Nomi API when it is not JSON
+func decodeBody(r *http.Request, v any) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return fail("InvalidContentType")
+	}
+
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		return &apiError{Type: "InvalidBody", Issues: []nomi.APIErrorIssues{{Code: "invalid_type", Message: err.Error()}}}
+	}
+
+	return nil
+}
+
+// pathID parses the id route parameter
+func pathID(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, fail("InvalidRouteParams")
+	}
+
+	return id, nil
+}
+
+// validateMessage checks the text of a message like the Nomi API does
+func (s *Server) validateMessage(text string) error {
+	if text == "" {
+		return &apiError{Type: "InvalidBody", Issues: []nomi.APIErrorIssues{{
+			Code:     "too_small",
+			Path:     []any{"messageText"},
+			Message:  "String must contain at least 1 character(s)",
+			Received: "",
+		}}}
+	}
+	if s.MaxMessageLength > 0 && utf8.RuneCountInString(text) > s.MaxMessageLength {
+		return fail("MessageLengthLimitExceeded")
+	}
+
+	return nil
+}
+
+func (s *Server) findNomi(id uuid.UUID) *nomi.Nomi {
+	for _, n := range s.nomis {
+		if n.UUID == id {
+			return n
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) findRoom(id uuid.UUID) *nomi.Room {
+	for _, room := range s.rooms {
+		if room.UUID == id {
+			return room
+		}
+	}
+
+	return nil
+}
+
+// addMessage records a message in the chat identified by id. It must be called with mu held
+func (s *Server) addMessage(id uuid.UUID, text string) nomi.Message {
+	msg := nomi.Message{UUID: uuid.New(), Text: text, Sent: time.Now().UTC()}
+	s.messages[id] = append(s.messages[id], msg)
+
+	return msg
+}
+
+// replyText picks the reply of n to text. It must be called with mu held
+func (s *Server) replyText(n nomi.Nomi, text string) string {
+	if queued := s.scripted[n.UUID]; len(queued) > 0 {
+		s.scripted[n.UUID] = queued[1:]
+		return queued[0]
+	}
+
+	return s.reply(n, text)
+}
+
+func (s *Server) getNomis(w http.ResponseWriter, r *http.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := nomi.GetNomisResponse{Nomis: []nomi.Nomi{}}
+	for _, n := range s.nomis {
+		res.Nomis = append(res.Nomis, *n)
+	}
+	writeJSON(w, http.StatusOK, res)
+
+	return nil
+}
+
+func (s *Server) getNomi(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.findNomi(id)
+	if n == nil {
+		return fail("NomiNotFound")
+	}
+	writeJSON(w, http.StatusOK, n)
+
+	return nil
+}
+
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+
+	var body nomi.SendMessageBody
+	if err := decodeBody(r, &body); err != nil {
+		return err
+	}
+	if err := s.validateMessage(body.MessageText); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.findNomi(id)
+	if n == nil {
+		return fail("NomiNotFound")
+	}
+
+	res := nomi.SendMessageResponse{
+		SentMessage:  s.addMessage(id, body.MessageText),
+		ReplyMessage: s.addMessage(id, s.replyText(*n, body.MessageText)),
+	}
+	writeJSON(w, http.StatusOK, res)
+
+	return nil
+}
+
+func (s *Server) getRooms(w http.ResponseWriter, r *http.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := nomi.GetRoomsResponse{Rooms: []nomi.Room{}}
+	for _, room := range s.rooms {
+		res.Rooms = append(res.Rooms, *room)
+	}
+	writeJSON(w, http.StatusOK, res)
+
+	return nil
+}
+
+// roomNomis resolves the members of a Room, failing like the Nomi API for invalid counts. It must be called with mu held
+func (s *Server) roomNomis(ids []uuid.UUID) ([]nomi.Nomi, error) {
+	if len(ids) < 1 {
+		return nil, fail("RoomNomiCountTooSmall")
+	}
+	if len(ids) > 10 {
+		return nil, fail("RoomNomiCountTooLarge")
+	}
+
+	nomis := make([]nomi.Nomi, 0, len(ids))
+	for _, id := range ids {
+		n := s.findNomi(id)
+		if n == nil {
+			return nil, fail("NomiNotFound")
+		}
+		nomis = append(nomis, *n)
+	}
+
+	return nomis, nil
+}
+
+func (s *Server) createRoom(w http.ResponseWriter, r *http.Request) error {
+	var body nomi.CreateRoomBody
+	if err := decodeBody(r, &body); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.MaxRooms > 0 && len(s.rooms) >= s.MaxRooms {
+		return fail("ExceededRoomLimit")
+	}
+
+	nomis, err := s.roomNomis(body.NomiUUIDs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	room := &nomi.Room{
+		UUID:                  uuid.New(),
+		Name:                  body.Name,
+		Created:               now,
+		Updated:               now,
+		Status:                nomi.StatusDefault,
+		BackchannelingEnabled: body.BackchannelingEnabled,
+		Note:                  body.Note,
+		Nomis:                 nomis,
+	}
+	s.rooms = append(s.rooms, room)
+	writeJSON(w, http.StatusOK, room)
+
+	return nil
+}
+
+func (s *Server) getRoom(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room := s.findRoom(id)
+	if room == nil {
+		return fail("RoomNotFound")
+	}
+	writeJSON(w, http.StatusOK, room)
+
+	return nil
+}
+
+func (s *Server) updateRoom(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+
+	var body nomi.UpdateRoomBody
+	if err := decodeBody(r, &body); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room := s.findRoom(id)
+	if room == nil {
+		return fail("RoomNotFound")
+	}
+
+	if body.NomiUUIDs != nil {
+		nomis, err := s.roomNomis(body.NomiUUIDs)
+		if err != nil {
+			return err
+		}
+		room.Nomis = nomis
+	}
+	if body.Name != nil {
+		room.Name = *body.Name
+	}
+	if body.Note != nil {
+		room.Note = *body.Note
+	}
+	if body.BackchannelingEnabled != nil {
+		room.BackchannelingEnabled = *body.BackchannelingEnabled
+	}
+	room.Updated = time.Now().UTC()
+	writeJSON(w, http.StatusOK, room)
+
+	return nil
+}
+
+func (s *Server) deleteRoom(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, room := range s.rooms {
+		if room.UUID == id {
+			s.rooms = append(s.rooms[:i], s.rooms[i+1:]...)
+			delete(s.messages, id)
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+	}
+
+	return fail("RoomNotFound")
+}
+
+// usableRoom finds a Room that can receive messages. It must be called with mu held
+func (s *Server) usableRoom(id uuid.UUID) (*nomi.Room, error) {
+	room := s.findRoom(id)
+	if room == nil {
+		return nil, fail("RoomNotFound")
+	}
+	if room.Status == nomi.StatusCreating {
+		return nil, fail("RoomStillCreating")
+	}
+
+	return room, nil
+}
+
+func (s *Server) sendRoomMessage(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+
+	var body nomi.SendRoomMessageBody
+	if err := decodeBody(r, &body); err != nil {
+		return err
+	}
+	if err := s.validateMessage(body.MessageText); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.usableRoom(id); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, nomi.SendRoomMessageResponse{SentMessage: s.addMessage(id, body.MessageText)})
+
+	return nil
+}
+
+func (s *Server) requestNomiRoomMessage(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r)
+	if err != nil {
+		return err
+	}
+
+	var body nomi.RequestNomiRoomMessageBody
+	if err := decodeBody(r, &body); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, err := s.usableRoom(id)
+	if err != nil {
+		return err
+	}
+
+	var member *nomi.Nomi
+	for i := range room.Nomis {
+		if room.Nomis[i].UUID == body.NomiUUID {
+			member = &room.Nomis[i]
+		}
+	}
+	if member == nil {
+		return fail("RoomNomiNotFound")
+	}
+
+	var last string
+	if messages := s.messages[id]; len(messages) > 0 {
+		last = messages[len(messages)-1].Text
+	}
+	reply := s.addMessage(id, s.replyText(*member, last))
+	writeJSON(w, http.StatusOK, nomi.RequestNomiMessageResponse{ReplyMessage: reply})
+
+	return nil
+}
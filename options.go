@@ -0,0 +1,68 @@
+package nomi
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the address of the public Nomi API
+	DefaultBaseURL = "https://api.nomi.ai/v1/"
+	// DefaultUserAgent is sent with every request unless overridden with WithUserAgent
+	DefaultUserAgent = "nomi-go-sdk"
+)
+
+// Option configures the client returned by NewClient
+type Option func(*api)
+
+// WithHTTPClient makes the client send its requests through c instead of http.DefaultClient
+func WithHTTPClient(c *http.Client) Option {
+	return func(a *api) {
+		if c != nil {
+			a.httpClient = c
+		}
+	}
+}
+
+// WithBaseURL points the client at a different Nomi API server, e.g. a staging or local one
+func WithBaseURL(baseURL string) Option {
+	return func(a *api) {
+		a.baseUrl = baseURL
+	}
+}
+
+// WithUserAgent overrides the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(a *api) {
+		a.userAgent = userAgent
+	}
+}
+
+// WithHeader adds a header that is sent with every request. The Authorization header is always set from the API key
+func WithHeader(key, value string) Option {
+	return func(a *api) {
+		a.headers.Add(key, value)
+	}
+}
+
+// WithTimeout limits the time each request may take, including reading the response body.
+// It applies to the client given with WithHTTPClient too, without modifying it
+func WithTimeout(timeout time.Duration) Option {
+	return func(a *api) {
+		a.timeout = timeout
+	}
+}
+
+// setHeaders adds the headers common to every request made by the client
+func (a api) setHeaders(req *http.Request) {
+	for key, values := range a.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	if a.userAgent != "" {
+		req.Header.Set("User-Agent", a.userAgent)
+	}
+	req.Header.Set("Authorization", a.apiKey)
+}
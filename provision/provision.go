@@ -0,0 +1,337 @@
+// Package provision manages the Rooms of an account as code. A Spec lists the Rooms that should exist, Plan compares
+// it with the Rooms returned by GetRooms, and Apply creates, updates and deletes Rooms to converge:
+//
+//	spec, err := provision.Load("rooms.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	plan, err := provision.NewPlan(ctx, client, spec)
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Print(plan) // dry run
+//	err = plan.Apply(ctx, client)
+//
+// Rooms are matched by name, which must be unique. Specs are YAML or JSON documents:
+//
+//	prune: true
+//	rooms:
+//	  - name: Book club
+//	    note: We discuss the book of the month
+//	    backchanneling: true
+//	    nomis: [Alice, Bob]
+package provision
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Limits of the Nomi API on Rooms
+const (
+	MaxRooms     = 10
+	MinRoomNomis = 1
+	MaxRoomNomis = 10
+)
+
+// ErrEmptySpec is returned by Parse for a document without any content, e.g. only comments. A spec without Rooms
+// must say so with "rooms: []"
+var ErrEmptySpec = errors.New("the spec is empty")
+
+// Spec is the desired state of the Rooms of an account
+type Spec struct {
+	Rooms []RoomSpec `yaml:"rooms" json:"rooms"`
+	// Prune deletes the Rooms that are not in the spec. Without it, they are left alone
+	Prune bool `yaml:"prune" json:"prune"`
+}
+
+// RoomSpec is the desired state of a Room
+type RoomSpec struct {
+	Name           string `yaml:"name" json:"name"`
+	Note           string `yaml:"note" json:"note"`
+	Backchanneling bool   `yaml:"backchanneling" json:"backchanneling"`
+	// Nomis are the members of the Room, by name or UUID
+	Nomis []string `yaml:"nomis" json:"nomis"`
+}
+
+// Load reads the spec in the YAML or JSON file at path
+func Load(path string) (Spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Spec{}, err
+	}
+
+	spec, err := Parse(b)
+	if err != nil {
+		return Spec{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return spec, nil
+}
+
+// Parse decodes a YAML or JSON spec, refusing unknown fields and empty documents
+func Parse(b []byte) (Spec, error) {
+	var spec Spec
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	err := dec.Decode(&spec)
+	if errors.Is(err, io.EOF) {
+		return Spec{}, ErrEmptySpec
+	}
+	if err != nil {
+		return Spec{}, err
+	}
+
+	return spec, nil
+}
+
+// Validate checks the spec against the limits of the API, without calling it
+func (s Spec) Validate() error {
+	names := map[string]bool{}
+	for _, room := range s.Rooms {
+		if strings.TrimSpace(room.Name) == "" {
+			return fmt.Errorf("a room has no name")
+		}
+		if names[room.Name] {
+			return fmt.Errorf("room %q is listed twice", room.Name)
+		}
+		names[room.Name] = true
+
+		if len(room.Nomis) < MinRoomNomis {
+			return fmt.Errorf("room %q has no Nomi: %w", room.Name, nomi.RoomNomiCountTooSmall)
+		}
+		if len(room.Nomis) > MaxRoomNomis {
+			return fmt.Errorf("room %q has %d Nomis: %w", room.Name, len(room.Nomis), nomi.RoomNomiCountTooLarge)
+		}
+	}
+	if len(s.Rooms) > MaxRooms {
+		return fmt.Errorf("the spec has %d rooms: %w", len(s.Rooms), nomi.ExceededRoomLimit)
+	}
+
+	return nil
+}
+
+// Action is what a Change does to a Room
+type Action string
+
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+// Change is a call to the API planned to converge to the spec
+type Change struct {
+	Action Action
+	// Room is the current Room for updates and deletions
+	Room *nomi.Room
+	// Name is the name of the Room
+	Name   string
+	Create *nomi.CreateRoomBody
+	Update *nomi.UpdateRoomBody
+	// Diff describes the changes, one per line, for the plan output
+	Diff []string
+}
+
+func (c Change) String() string {
+	symbol := map[Action]string{Create: "+", Update: "~", Delete: "-"}[c.Action]
+	s := fmt.Sprintf("%s %s room %q", symbol, c.Action, c.Name)
+	for _, line := range c.Diff {
+		s += "\n    " + line
+	}
+
+	return s
+}
+
+// Plan is the list of changes converging to a spec
+type Plan struct {
+	Changes []Change
+}
+
+// String describes the changes, or says there is nothing to do
+func (p Plan) String() string {
+	if len(p.Changes) == 0 {
+		return "No changes, the rooms match the spec.\n"
+	}
+
+	var b strings.Builder
+	counts := map[Action]int{}
+	for _, change := range p.Changes {
+		b.WriteString(change.String() + "\n")
+		counts[change.Action]++
+	}
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete.\n", counts[Create], counts[Update], counts[Delete])
+
+	return b.String()
+}
+
+// NewPlan compares spec with the Rooms of the account and plans the changes converging to it. It fails if the spec is
+// invalid, names a Nomi that does not exist or whose name is ambiguous, or would leave more than MaxRooms Rooms
+func NewPlan(ctx context.Context, client nomi.API, spec Spec) (Plan, error) {
+	err := spec.Validate()
+	if err != nil {
+		return Plan{}, err
+	}
+
+	nomis, err := client.GetNomisContext(ctx)
+	if err != nil {
+		return Plan{}, err
+	}
+	rooms, err := client.GetRoomsContext(ctx)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	current := map[string]*nomi.Room{}
+	for i, room := range rooms.Rooms {
+		if _, ok := current[room.Name]; ok {
+			return Plan{}, fmt.Errorf("several rooms are called %q, rename them to match them with the spec", room.Name)
+		}
+		current[room.Name] = &rooms.Rooms[i]
+	}
+
+	var plan Plan
+	wanted := map[string]bool{}
+	for _, want := range spec.Rooms {
+		wanted[want.Name] = true
+
+		ids, err := resolveNomis(nomis.Nomis, want.Nomis)
+		if err != nil {
+			return Plan{}, fmt.Errorf("room %q: %w", want.Name, err)
+		}
+
+		room, ok := current[want.Name]
+		if !ok {
+			plan.Changes = append(plan.Changes, Change{
+				Action: Create,
+				Name:   want.Name,
+				Create: &nomi.CreateRoomBody{Name: want.Name, Note: want.Note, BackchannelingEnabled: want.Backchanneling, NomiUUIDs: ids},
+				Diff:   []string{fmt.Sprintf("nomis: %s", nomiNames(nomis.Nomis, ids))},
+			})
+			continue
+		}
+
+		if change, ok := diffRoom(room, want, ids, nomis.Nomis); ok {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	kept := 0
+	for _, room := range rooms.Rooms {
+		switch {
+		case wanted[room.Name]:
+		case spec.Prune:
+			plan.Changes = append(plan.Changes, Change{Action: Delete, Room: &room, Name: room.Name})
+		default:
+			kept++
+		}
+	}
+	if total := kept + len(spec.Rooms); total > MaxRooms {
+		return Plan{}, fmt.Errorf("the account would have %d rooms, %d of them not in the spec: %w", total, kept, nomi.ExceededRoomLimit)
+	}
+
+	return plan, nil
+}
+
+// diffRoom plans the update of room to want, if it differs
+func diffRoom(room *nomi.Room, want RoomSpec, ids []uuid.UUID, nomis []nomi.Nomi) (Change, bool) {
+	change := Change{Action: Update, Room: room, Name: room.Name, Update: &nomi.UpdateRoomBody{}}
+
+	if room.Note != want.Note {
+		change.Update.Note = &want.Note
+		change.Diff = append(change.Diff, fmt.Sprintf("note: %q -> %q", room.Note, want.Note))
+	}
+	if room.BackchannelingEnabled != want.Backchanneling {
+		change.Update.BackchannelingEnabled = &want.Backchanneling
+		change.Diff = append(change.Diff, fmt.Sprintf("backchanneling: %t -> %t", room.BackchannelingEnabled, want.Backchanneling))
+	}
+
+	var currentIDs []uuid.UUID
+	for _, n := range room.Nomis {
+		currentIDs = append(currentIDs, n.UUID)
+	}
+	if !sameMembers(currentIDs, ids) {
+		change.Update.NomiUUIDs = ids
+		change.Diff = append(change.Diff, fmt.Sprintf("nomis: %s -> %s", nomiNames(nomis, currentIDs), nomiNames(nomis, ids)))
+	}
+
+	return change, len(change.Diff) > 0
+}
+
+func sameMembers(a, b []uuid.UUID) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	compare := func(x, y uuid.UUID) int { return strings.Compare(x.String(), y.String()) }
+	slices.SortFunc(a, compare)
+	slices.SortFunc(b, compare)
+
+	return slices.Equal(a, b)
+}
+
+// resolveNomis finds the UUIDs of the Nomis named by refs, by UUID or case-insensitive name
+func resolveNomis(nomis []nomi.Nomi, refs []string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, ref := range refs {
+		n, err := nomi.FindNomi(nomis, ref)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(ids, n.UUID) {
+			return nil, fmt.Errorf("Nomi %q is listed twice", ref)
+		}
+		ids = append(ids, n.UUID)
+	}
+
+	return ids, nil
+}
+
+// nomiNames lists the names of the Nomis with ids
+func nomiNames(nomis []nomi.Nomi, ids []uuid.UUID) string {
+	var names []string
+	for _, id := range ids {
+		name := id.String()
+		for _, n := range nomis {
+			if n.UUID == id {
+				name = n.Name
+			}
+		}
+		names = append(names, name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// Apply makes the changes of the plan, deleting Rooms first to make room for the new ones. It stops at the first
+// error, the changes made before it staying applied
+func (p Plan) Apply(ctx context.Context, client nomi.API) error {
+	order := map[Action]int{Delete: 0, Update: 1, Create: 2}
+	changes := slices.Clone(p.Changes)
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return order[a.Action] - order[b.Action]
+	})
+
+	for _, change := range changes {
+		var err error
+		switch change.Action {
+		case Delete:
+			_, err = client.DeleteRoomContext(ctx, change.Room.UUID.String())
+		case Update:
+			_, err = client.UpdateRoomContext(ctx, change.Room.UUID.String(), *change.Update)
+		case Create:
+			_, err = client.CreateRoomContext(ctx, *change.Create)
+		}
+		if err != nil {
+			return fmt.Errorf("%s room %q: %w", change.Action, change.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package nomi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// operation describes a single call to the Nomi API
+type operation struct {
+	// name is the name of the API method making the call, e.g. "SendMessage"
+	name   string
+	method string
+	path   []string
+	// body is encoded as JSON and sent with the request when it is not nil
+	body any
+	// out receives the decoded response body when it is not nil
+	out any
+	// message is set for operations sending a user message or requesting a reply, which count towards the daily quota
+	message bool
+	// waitsReady is set for operations failing with NotReady right after the creation of a Nomi, which
+	// WithWaitForReady makes wait for
+	waitsReady bool
+	// invalid is set when the parameters were rejected before the call, e.g. an ID that is not a UUID. It is
+	// returned once the call is in the pipeline so that the interceptors see it
+	invalid error
+}
+
+// ids returns the UUIDs of the Nomi and the Room op is about, found in its path and body. An ID of the path that was
+// rejected by parseID is left out
+func (op operation) ids() (nomiID string, roomID string) {
+	if len(op.path) > 1 && !errors.Is(op.invalid, InvalidRouteParams) {
+		switch op.path[0] {
+		case "nomis":
+			nomiID = op.path[1]
+		case "rooms":
+			roomID = op.path[1]
+		}
+	}
+	if body, ok := op.body.(RequestNomiRoomMessageBody); ok {
+		nomiID = body.NomiUUID.String()
+	}
+
+	return nomiID, roomID
+}
+
+// do executes op and decodes its response. Every API method goes through here so that auth, body
+// encoding, status handling, error parsing, retries and interceptors behave the same for all of them
+func (a api) do(ctx context.Context, op operation) (err error) {
+	u, err := url.JoinPath(a.baseUrl, op.path...)
+	if err != nil {
+		return err
+	}
+
+	call := &Call{Operation: op.name, Method: op.method, URL: u, Body: op.body, Header: http.Header{}}
+	call.NomiID, call.RoomID = op.ids()
+	start := time.Now()
+	for i, interceptor := range a.interceptors {
+		if interceptor.BeforeSend == nil {
+			continue
+		}
+
+		next, err := interceptor.BeforeSend(ctx, call)
+		if err != nil {
+			call.Duration = time.Since(start)
+			intercepted(ctx, a.interceptors[:i], call, err)
+			return err
+		}
+		if next != nil {
+			ctx = next
+		}
+	}
+
+	err = a.run(ctx, op, call)
+	call.Duration = time.Since(start)
+	if err != nil && call.Attempts == 0 && a.logger != nil {
+		a.logger.rejected(ctx, call, err)
+	}
+	if err == nil {
+		call.Result = op.out
+	}
+	intercepted(ctx, a.interceptors, call, err)
+
+	return err
+}
+
+// run encodes the body of op and makes the attempts at it, waiting for the Nomi to be ready if needed
+func (a api) run(ctx context.Context, op operation, call *Call) (err error) {
+	if op.invalid != nil {
+		return op.invalid
+	}
+
+	var payload []byte
+	if op.body != nil {
+		payload, err = json.Marshal(op.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	if op.message {
+		if err := a.quota.reserve(); err != nil {
+			return err
+		}
+		defer func() { a.quota.release(err) }()
+	}
+
+	if a.waitReady == nil || !op.waitsReady {
+		return a.retryLoop(ctx, op, call, payload)
+	}
+
+	deadline := time.Now().Add(a.waitReady.timeout)
+	for poll := 1; ; poll++ {
+		err = a.retryLoop(ctx, op, call, payload)
+		if !errors.Is(err, NotReady) {
+			return err
+		}
+
+		delay := a.waitReady.poll.backoff(poll, err)
+		if a.waitReady.timeout > 0 && time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return sleepErr
+		}
+	}
+}
+
+// retryLoop makes the attempts at op allowed by the retry policy
+func (a api) retryLoop(ctx context.Context, op operation, call *Call, payload []byte) (err error) {
+	attempts := a.retry.attempts(ctx)
+	for attempt := 1; ; attempt++ {
+		err = a.send(ctx, op, call, payload)
+		if err == nil || attempts == 1 {
+			return err
+		}
+
+		retrying := attempt < attempts && a.retry.retryable(op, err)
+		var delay time.Duration
+		if retrying {
+			delay = a.retry.backoff(attempt, err)
+		}
+
+		if a.retry.OnAttempt != nil {
+			a.retry.OnAttempt(Attempt{
+				Operation: op.name,
+				Number:    attempt,
+				Err:       err,
+				Retrying:  retrying,
+				Delay:     delay,
+			})
+		}
+
+		if !retrying {
+			return err
+		}
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return sleepErr
+		}
+	}
+}
+
+// send makes a single attempt at op
+func (a api) send(ctx context.Context, op operation, call *Call, payload []byte) error {
+	if a.limiter != nil {
+		if err := a.limiter.wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	call.Attempts++
+	req, err := http.NewRequestWithContext(ctx, op.method, call.URL, reqBody)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	a.setHeaders(req)
+	for key, values := range call.Header {
+		req.Header[key] = values
+	}
+
+	start := time.Now()
+	status, b, err := a.roundTrip(ctx, req)
+	if status != 0 {
+		call.StatusCode = status
+	}
+	if a.logger != nil {
+		a.logger.log(ctx, call, req, status, time.Since(start), payload, b, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if op.out == nil || len(b) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(b, op.out)
+}
+
+// roundTrip sends req and reads the response, returning its status, its body and, for statuses other than 2xx, an
+// *Error. The status is zero when no response was received
+func (a api) roundTrip(ctx context.Context, req *http.Request) (int, []byte, error) {
+	response, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, contextError(ctx, err)
+	}
+	defer response.Body.Close()
+
+	b, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, nil, contextError(ctx, err)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, b, parseError(response, b)
+	}
+
+	return response.StatusCode, b, nil
+}
+
+// parseID validates a route parameter before it is put in a URL. An invalid id is returned escaped along with the
+// error, for the URL of the rejected call to show it
+func parseID(id string) (string, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return url.PathEscape(id), InvalidRouteParams
+	}
+
+	return parsed.String(), nil
+}
@@ -0,0 +1,319 @@
+package nomi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AmbiguousNameError is returned when several Nomis or Rooms have the name being resolved
+type AmbiguousNameError struct {
+	// Kind is "Nomi" or "Room"
+	Kind  string
+	Name  string
+	UUIDs []uuid.UUID
+}
+
+func (e *AmbiguousNameError) Error() string {
+	ids := make([]string, len(e.UUIDs))
+	for i, id := range e.UUIDs {
+		ids[i] = id.String()
+	}
+
+	return fmt.Sprintf("%d %ss are called %q, use the UUID of one of them: %s", len(e.UUIDs), e.Kind, e.Name, strings.Join(ids, ", "))
+}
+
+// Resolver finds Nomis and Rooms by name with GetNomis and GetRooms, keeping the lists for a while. Names are matched
+// ignoring case. It is safe for concurrent use
+type Resolver struct {
+	client API
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	nomis cached[[]Nomi]
+	rooms cached[[]Room]
+}
+
+type cached[T any] struct {
+	value   T
+	fetched time.Time
+}
+
+func (c cached[T]) fresh(ttl time.Duration) bool {
+	return !c.fetched.IsZero() && time.Since(c.fetched) < ttl
+}
+
+// NewResolver creates a Resolver using client, which keeps the lists of Nomis and Rooms for ttl. A name missing from
+// a kept list is looked up again in a new one, so new Nomis and Rooms are found before ttl has elapsed
+func NewResolver(client API, ttl time.Duration) *Resolver {
+	return &Resolver{client: client, ttl: ttl}
+}
+
+// Invalidate drops the lists kept by the resolver
+func (r *Resolver) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nomis = cached[[]Nomi]{}
+	r.rooms = cached[[]Room]{}
+}
+
+// InvalidateRooms drops the list of Rooms, e.g. after one was created, renamed or deleted
+func (r *Resolver) InvalidateRooms() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rooms = cached[[]Room]{}
+}
+
+// Nomi returns the Nomi with the UUID or the name nameOrID. It returns an error matching NotFound if there is none,
+// and an *AmbiguousNameError if several Nomis have the name
+func (r *Resolver) Nomi(ctx context.Context, nameOrID string) (Nomi, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return resolve(ctx, &r.nomis, r.ttl, nameOrID, "Nomi", NotFound,
+		func(ctx context.Context) ([]Nomi, error) {
+			res, err := r.client.GetNomisContext(ctx)
+			return res.Nomis, err
+		},
+		nomiKey,
+	)
+}
+
+// Room returns the Room with the UUID or the name nameOrID. It returns an error matching RoomNotFound if there is
+// none, and an *AmbiguousNameError if several Rooms have the name
+func (r *Resolver) Room(ctx context.Context, nameOrID string) (Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return resolve(ctx, &r.rooms, r.ttl, nameOrID, "Room", RoomNotFound,
+		func(ctx context.Context) ([]Room, error) {
+			res, err := r.client.GetRoomsContext(ctx)
+			return res.Rooms, err
+		},
+		roomKey,
+	)
+}
+
+// NomiID returns the UUID of the Nomi nameOrID. UUIDs are returned as they are, without calling the API
+func (r *Resolver) NomiID(ctx context.Context, nameOrID string) (string, error) {
+	if id, err := uuid.Parse(nameOrID); err == nil {
+		return id.String(), nil
+	}
+
+	n, err := r.Nomi(ctx, nameOrID)
+	return n.UUID.String(), err
+}
+
+// RoomID returns the UUID of the Room nameOrID. UUIDs are returned as they are, without calling the API
+func (r *Resolver) RoomID(ctx context.Context, nameOrID string) (string, error) {
+	if id, err := uuid.Parse(nameOrID); err == nil {
+		return id.String(), nil
+	}
+
+	room, err := r.Room(ctx, nameOrID)
+	return room.UUID.String(), err
+}
+
+// resolve finds nameOrID in the list kept in c, fetching a new list when it is stale or misses nameOrID
+func resolve[T any](ctx context.Context, c *cached[[]T], ttl time.Duration, nameOrID string, kind string, notFound error,
+	fetch func(context.Context) ([]T, error), key func(T) (uuid.UUID, string)) (T, error) {
+	item, err := find(c.value, nameOrID, kind, notFound, key)
+	if errors.Is(err, notFound) || !c.fresh(ttl) {
+		items, fetchErr := fetch(ctx)
+		if fetchErr != nil {
+			return item, fetchErr
+		}
+		*c = cached[[]T]{value: items, fetched: time.Now()}
+		item, err = find(c.value, nameOrID, kind, notFound, key)
+	}
+
+	return item, err
+}
+
+// find returns the item of items with the UUID or the case-insensitive name nameOrID
+func find[T any](items []T, nameOrID string, kind string, notFound error, key func(T) (uuid.UUID, string)) (T, error) {
+	var zero T
+
+	id, idErr := uuid.Parse(nameOrID)
+	var found []T
+	for _, item := range items {
+		itemID, name := key(item)
+		if (idErr == nil && itemID == id) || (idErr != nil && strings.EqualFold(name, nameOrID)) {
+			found = append(found, item)
+		}
+	}
+
+	switch {
+	case len(found) == 1:
+		return found[0], nil
+	case len(found) == 0 && idErr == nil:
+		return zero, fmt.Errorf("no %s has the UUID %s: %w", kind, id, notFound)
+	case len(found) == 0:
+		return zero, fmt.Errorf("no %s is called %q: %w", kind, nameOrID, notFound)
+	default:
+		ambiguous := &AmbiguousNameError{Kind: kind, Name: nameOrID}
+		for _, item := range found {
+			itemID, _ := key(item)
+			ambiguous.UUIDs = append(ambiguous.UUIDs, itemID)
+		}
+		return zero, ambiguous
+	}
+}
+
+func nomiKey(n Nomi) (uuid.UUID, string) { return n.UUID, n.Name }
+
+func roomKey(room Room) (uuid.UUID, string) { return room.UUID, room.Name }
+
+// FindNomi returns the Nomi of nomis with the UUID or the name nameOrID, matched like a Resolver does, e.g. among the
+// Nomis of a Room. It returns an error matching NotFound if there is none, and an *AmbiguousNameError if several
+// Nomis have the name
+func FindNomi(nomis []Nomi, nameOrID string) (Nomi, error) {
+	return find(nomis, nameOrID, "Nomi", NotFound, nomiKey)
+}
+
+// FindRoom returns the Room of rooms with the UUID or the name nameOrID, matched like a Resolver does. It returns an
+// error matching RoomNotFound if there is none, and an *AmbiguousNameError if several Rooms have the name
+func FindRoom(rooms []Room, nameOrID string) (Room, error) {
+	return find(rooms, nameOrID, "Room", RoomNotFound, roomKey)
+}
+
+// WithNameResolution wraps client so that every method taking the ID of a Nomi or Room also accepts its name,
+// resolved with a Resolver keeping the lists for ttl. The Rooms are looked up again after CreateRoom, UpdateRoom and
+// DeleteRoom
+func WithNameResolution(client API, ttl time.Duration) API {
+	return &resolvingAPI{API: client, resolver: NewResolver(client, ttl)}
+}
+
+type resolvingAPI struct {
+	API
+	resolver *Resolver
+}
+
+func (r *resolvingAPI) Quota() QuotaStats {
+	stats, _ := QuotaOf(r.API)
+	return stats
+}
+
+func (r *resolvingAPI) quotaOf() (QuotaStats, bool) {
+	return QuotaOf(r.API)
+}
+
+func (r *resolvingAPI) GetNomi(nomiID string) (GetNomiResponse, error) {
+	return r.GetNomiContext(context.Background(), nomiID)
+}
+
+func (r *resolvingAPI) GetNomiContext(ctx context.Context, nomiID string) (GetNomiResponse, error) {
+	id, err := r.resolver.NomiID(ctx, nomiID)
+	if err != nil {
+		return GetNomiResponse{}, err
+	}
+
+	return r.API.GetNomiContext(ctx, id)
+}
+
+func (r *resolvingAPI) SendMessage(nomiID string, body SendMessageBody) (SendMessageResponse, error) {
+	return r.SendMessageContext(context.Background(), nomiID, body)
+}
+
+func (r *resolvingAPI) SendMessageContext(ctx context.Context, nomiID string, body SendMessageBody) (SendMessageResponse, error) {
+	id, err := r.resolver.NomiID(ctx, nomiID)
+	if err != nil {
+		return SendMessageResponse{}, err
+	}
+
+	return r.API.SendMessageContext(ctx, id, body)
+}
+
+func (r *resolvingAPI) CreateRoom(body CreateRoomBody) (CreateRoomResponse, error) {
+	return r.CreateRoomContext(context.Background(), body)
+}
+
+func (r *resolvingAPI) CreateRoomContext(ctx context.Context, body CreateRoomBody) (CreateRoomResponse, error) {
+	res, err := r.API.CreateRoomContext(ctx, body)
+	if err == nil {
+		r.resolver.InvalidateRooms()
+	}
+
+	return res, err
+}
+
+func (r *resolvingAPI) GetRoom(roomID string) (GetRoomResponse, error) {
+	return r.GetRoomContext(context.Background(), roomID)
+}
+
+func (r *resolvingAPI) GetRoomContext(ctx context.Context, roomID string) (GetRoomResponse, error) {
+	id, err := r.resolver.RoomID(ctx, roomID)
+	if err != nil {
+		return GetRoomResponse{}, err
+	}
+
+	return r.API.GetRoomContext(ctx, id)
+}
+
+func (r *resolvingAPI) SendRoomMessage(roomID string, body SendRoomMessageBody) (SendRoomMessageResponse, error) {
+	return r.SendRoomMessageContext(context.Background(), roomID, body)
+}
+
+func (r *resolvingAPI) SendRoomMessageContext(ctx context.Context, roomID string, body SendRoomMessageBody) (SendRoomMessageResponse, error) {
+	id, err := r.resolver.RoomID(ctx, roomID)
+	if err != nil {
+		return SendRoomMessageResponse{}, err
+	}
+
+	return r.API.SendRoomMessageContext(ctx, id, body)
+}
+
+func (r *resolvingAPI) RequestNomiRoomMessage(roomID string, body RequestNomiRoomMessageBody) (RequestNomiMessageResponse, error) {
+	return r.RequestNomiRoomMessageContext(context.Background(), roomID, body)
+}
+
+func (r *resolvingAPI) RequestNomiRoomMessageContext(ctx context.Context, roomID string, body RequestNomiRoomMessageBody) (RequestNomiMessageResponse, error) {
+	id, err := r.resolver.RoomID(ctx, roomID)
+	if err != nil {
+		return RequestNomiMessageResponse{}, err
+	}
+
+	return r.API.RequestNomiRoomMessageContext(ctx, id, body)
+}
+
+func (r *resolvingAPI) UpdateRoom(roomID string, body UpdateRoomBody) (UpdateRoomResponse, error) {
+	return r.UpdateRoomContext(context.Background(), roomID, body)
+}
+
+func (r *resolvingAPI) UpdateRoomContext(ctx context.Context, roomID string, body UpdateRoomBody) (UpdateRoomResponse, error) {
+	id, err := r.resolver.RoomID(ctx, roomID)
+	if err != nil {
+		return UpdateRoomResponse{}, err
+	}
+
+	res, err := r.API.UpdateRoomContext(ctx, id, body)
+	if err == nil {
+		r.resolver.InvalidateRooms()
+	}
+
+	return res, err
+}
+
+func (r *resolvingAPI) DeleteRoom(roomID string) (bool, error) {
+	return r.DeleteRoomContext(context.Background(), roomID)
+}
+
+func (r *resolvingAPI) DeleteRoomContext(ctx context.Context, roomID string) (bool, error) {
+	id, err := r.resolver.RoomID(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+
+	success, err := r.API.DeleteRoomContext(ctx, id)
+	if err == nil {
+		r.resolver.InvalidateRooms()
+	}
+
+	return success, err
+}
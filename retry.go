@@ -0,0 +1,205 @@
+package nomi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the client retries operations failing with transient errors
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Values below 2 disable retries
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, except when the server asks for a longer one with Retry-After
+	MaxBackoff time.Duration
+	// Multiplier is the factor applied to the delay after each attempt
+	Multiplier float64
+	// Jitter is the fraction of each delay that is randomized, between 0 and 1
+	Jitter float64
+	// Retryable decides whether an error is worth another attempt, for every operation. Defaults to IsTransient for
+	// the operations safe to repeat, and to the temporary error types of the Nomi API and 429 responses for the POST
+	// operations, see WithRetry
+	Retryable func(err error) bool
+	// OnAttempt, if set, is called after every failed attempt
+	OnAttempt func(attempt Attempt)
+}
+
+// Attempt describes a failed attempt at an operation, as reported to RetryPolicy.OnAttempt
+type Attempt struct {
+	// Operation is the name of the API method, e.g. "SendMessage"
+	Operation string
+	// Number is the number of the attempt, starting at 1
+	Number int
+	// Err is the error the attempt failed with
+	Err error
+	// Retrying is set when another attempt will be made after Delay
+	Retrying bool
+	Delay    time.Duration
+}
+
+// DefaultRetryPolicy makes up to 4 attempts, waiting 500ms, 1s and 2s (±20%) between them
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// WithRetry makes the client retry operations failing with errors that policy considers retryable.
+//
+// The POST operations, CreateRoom, SendMessage, SendRoomMessage and RequestNomiRoomMessage, are not safe to repeat: a
+// request that failed with a 5xx response or a network error may still have created the Room or sent the message.
+// Unless policy has its own Retryable, they are only retried on the temporary error types of the Nomi API, on 429
+// responses and when the connection could not be made. Keep in mind that NoReply is one of them, and that retrying a
+// SendMessage that failed with NoReply sends the message again
+func WithRetry(policy RetryPolicy) Option {
+	return func(a *api) {
+		a.retry = &policy
+	}
+}
+
+type noRetryKey struct{}
+
+// WithoutRetry returns a context that disables retries for the calls made with it
+func WithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// IsTransient reports whether err is likely to go away by trying again: the errors the Nomi API documents as temporary,
+// 429 and 5xx responses, and network errors. Context cancellation is never transient
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if temporary(err) {
+		return true
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// attempts returns how many times op may be attempted with ctx
+func (p *RetryPolicy) attempts(ctx context.Context) int {
+	if p == nil || p.MaxAttempts < 2 {
+		return 1
+	}
+	if disabled, _ := ctx.Value(noRetryKey{}).(bool); disabled {
+		return 1
+	}
+
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) retryable(op operation, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	if op.method == http.MethodPost {
+		return notProcessed(err)
+	}
+
+	return IsTransient(err)
+}
+
+// temporary reports whether err is one of the errors the Nomi API documents as temporary
+func temporary(err error) bool {
+	return errors.Is(err, NoReply) ||
+		errors.Is(err, StillResponding) ||
+		errors.Is(err, NotReady) ||
+		errors.Is(err, RoomStillCreating) ||
+		errors.Is(err, RoomNomiNotReadyForMessage)
+}
+
+// notProcessed reports whether err shows that the API did not act on the request, so that an operation which is not
+// safe to repeat can be retried: the temporary error types of the Nomi API, 429 responses and failures to connect
+func notProcessed(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if temporary(err) {
+		return true
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// backoff returns the delay to wait after the given failed attempt, honoring a Retry-After header sent with err
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	if retryAfter, ok := retryAfter(err); ok {
+		return retryAfter
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// retryAfter parses the Retry-After header of a failed response, given either in seconds or as an HTTP date
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Header == nil {
+		return 0, false
+	}
+
+	value := apiErr.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+// sleep waits for d, returning early with the context's error if ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return contextError(ctx, ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
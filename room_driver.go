@@ -0,0 +1,167 @@
+package nomi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// StopReason tells why DriveRoom stopped
+type StopReason string
+
+const (
+	StopMaxTurns StopReason = "max turns"
+	StopDuration StopReason = "duration"
+	StopKeyword  StopReason = "keyword"
+	StopQuota    StopReason = "quota"
+	StopCanceled StopReason = "canceled"
+	StopNobody   StopReason = "nobody to reply"
+	StopError    StopReason = "error"
+)
+
+// DriveOptions configures a conversation between the Nomis of a Room run by DriveRoom. Zero values do not limit it
+type DriveOptions struct {
+	// Strategy picks the next Nomis to speak from the last reply. Defaults to RoundRobinTurns
+	Strategy TurnStrategy
+	// Interval is the pause between two turns
+	Interval time.Duration
+	// MaxTurns is the number of replies requested, including the failed ones
+	MaxTurns int
+	// MaxDuration stops the conversation once it has lasted this long. A request in flight is not interrupted
+	MaxDuration time.Duration
+	// StopWords stops the conversation after a reply containing one of them, ignoring case
+	StopWords []string
+	// QuotaReserve stops the conversation when the daily quota set with WithDailyQuota has this many messages left or
+	// fewer, keeping them for the user. The replies requested by DriveRoom count towards the quota
+	QuotaReserve int
+	// ErrorBackoff is the pause after a failed turn, growing with the failures in a row. Only its InitialBackoff,
+	// MaxBackoff, Multiplier and Jitter are used. Defaults to those of DefaultRetryPolicy when InitialBackoff is zero
+	ErrorBackoff RetryPolicy
+}
+
+// RoomEvent is emitted by DriveRoom for each turn, and once when it stops
+type RoomEvent struct {
+	// Turn is the number of the turn, starting at 1
+	Turn    int
+	Nomi    Nomi
+	Message Message
+	// Err is the error of the turn. The conversation goes on after transient errors such as NoReply, backing off while
+	// they last
+	Err error
+	// Stopped is set on the last event, which has no reply. Err is then the error that stopped the conversation, if any
+	Stopped StopReason
+}
+
+// DriveRoom makes the Nomis of room talk to each other by requesting their replies in turn, without any message from
+// the user. Each reply is emitted as an event on the returned channel, followed by a last event telling why the
+// conversation stopped, then the channel is closed. It stops when ctx is done, when a limit of opts is reached, on
+// LimitExceeded and on errors that are not transient. The events must be read until the channel is closed
+func DriveRoom(ctx context.Context, client API, room Room, opts DriveOptions) <-chan RoomEvent {
+	events := make(chan RoomEvent)
+
+	go func() {
+		defer close(events)
+
+		reason, err := driveRoom(ctx, client, room, opts, events)
+		events <- RoomEvent{Stopped: reason, Err: err}
+	}()
+
+	return events
+}
+
+func driveRoom(ctx context.Context, client API, room Room, opts DriveOptions, events chan<- RoomEvent) (StopReason, error) {
+	strategy := opts.Strategy
+	if strategy == nil {
+		strategy = RoundRobinTurns()
+	}
+
+	var deadline time.Time
+	if opts.MaxDuration > 0 {
+		deadline = time.Now().Add(opts.MaxDuration)
+	}
+	backoff := opts.ErrorBackoff
+	if backoff.InitialBackoff <= 0 {
+		backoff = DefaultRetryPolicy()
+	}
+
+	turn := 0
+	// failures counts the failed turns in a row, to back off while the errors last
+	failures := 0
+	var failure error
+	last := ""
+	for {
+		nomis := strategy.Next(room, last)
+		if len(nomis) == 0 {
+			return StopNobody, nil
+		}
+
+		for _, n := range nomis {
+			if opts.MaxTurns > 0 && turn >= opts.MaxTurns {
+				return StopMaxTurns, nil
+			}
+
+			pause := opts.Interval
+			if failures > 0 {
+				pause = max(pause, backoff.backoff(failures, failure))
+			}
+			if turn > 0 && pause > 0 {
+				if !deadline.IsZero() && time.Until(deadline) < pause {
+					return StopDuration, nil
+				}
+				if err := sleep(ctx, pause); err != nil {
+					return StopCanceled, err
+				}
+			}
+			if ctx.Err() != nil {
+				return StopCanceled, contextError(ctx, ctx.Err())
+			}
+			if !deadline.IsZero() && !time.Now().Before(deadline) {
+				return StopDuration, nil
+			}
+			if quota, _ := QuotaOf(client); quota.Limit > 0 && quota.Remaining <= opts.QuotaReserve {
+				return StopQuota, nil
+			}
+
+			turn++
+			res, err := client.RequestNomiRoomMessageContext(ctx, room.UUID.String(), RequestNomiRoomMessageBody{NomiUUID: n.UUID})
+			switch {
+			case ctx.Err() != nil:
+				return StopCanceled, contextError(ctx, ctx.Err())
+			case errors.Is(err, LimitExceeded):
+				return StopQuota, err
+			case err != nil && !IsTransient(err):
+				return StopError, err
+			}
+
+			select {
+			case events <- RoomEvent{Turn: turn, Nomi: n, Message: res.ReplyMessage, Err: err}:
+			case <-ctx.Done():
+				return StopCanceled, contextError(ctx, ctx.Err())
+			}
+
+			if err != nil {
+				failures++
+				failure = err
+				continue
+			}
+			failures = 0
+			last = res.ReplyMessage.Text
+			if containsAny(last, opts.StopWords) {
+				return StopKeyword, nil
+			}
+		}
+	}
+}
+
+// containsAny reports whether text contains one of words, ignoring case
+func containsAny(text string, words []string) bool {
+	text = strings.ToLower(text)
+	for _, word := range words {
+		if word != "" && strings.Contains(text, strings.ToLower(word)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,233 @@
+package nomi
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TurnStrategy picks the Nomis of room replying to the message sent by the user, in the order they reply
+type TurnStrategy interface {
+	Next(room Room, message string) []Nomi
+}
+
+// TurnStrategyFunc is a function used as a TurnStrategy
+type TurnStrategyFunc func(room Room, message string) []Nomi
+
+func (f TurnStrategyFunc) Next(room Room, message string) []Nomi {
+	return f(room, message)
+}
+
+// AllTurns makes every Nomi of the Room reply, in the order of Room.Nomis
+func AllTurns() TurnStrategy {
+	return TurnStrategyFunc(func(room Room, message string) []Nomi {
+		return slices.Clone(room.Nomis)
+	})
+}
+
+// RoundRobinTurns makes the Nomis of the Room reply one after the other, one per message
+func RoundRobinTurns() TurnStrategy {
+	var mu sync.Mutex
+	next := 0
+
+	return TurnStrategyFunc(func(room Room, message string) []Nomi {
+		if len(room.Nomis) == 0 {
+			return nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		n := room.Nomis[next%len(room.Nomis)]
+		next = (next + 1) % len(room.Nomis)
+		return []Nomi{n}
+	})
+}
+
+// RandomTurns makes a Nomi of the Room picked at random reply
+func RandomTurns() TurnStrategy {
+	return TurnStrategyFunc(func(room Room, message string) []Nomi {
+		if len(room.Nomis) == 0 {
+			return nil
+		}
+
+		return []Nomi{room.Nomis[rand.IntN(len(room.Nomis))]}
+	})
+}
+
+// WeightedTurns makes a Nomi picked at random reply, each Nomi being picked in proportion to its weight. Nomis
+// missing from weights have a weight of 1, and Nomis with a weight of 0 or less never reply
+func WeightedTurns(weights map[uuid.UUID]float64) TurnStrategy {
+	return TurnStrategyFunc(func(room Room, message string) []Nomi {
+		total := 0.0
+		for _, n := range room.Nomis {
+			total += nomiWeight(weights, n)
+		}
+		if total <= 0 {
+			return nil
+		}
+
+		pick := rand.Float64() * total
+		for _, n := range room.Nomis {
+			weight := nomiWeight(weights, n)
+			if weight > 0 && pick < weight {
+				return []Nomi{n}
+			}
+			pick -= weight
+		}
+
+		// Rounding errors can leave pick slightly above the last weight
+		for _, n := range slices.Backward(room.Nomis) {
+			if nomiWeight(weights, n) > 0 {
+				return []Nomi{n}
+			}
+		}
+		return nil
+	})
+}
+
+func nomiWeight(weights map[uuid.UUID]float64, n Nomi) float64 {
+	weight, ok := weights[n.UUID]
+	if !ok {
+		return 1
+	}
+
+	return max(weight, 0)
+}
+
+// MentionedTurns makes the Nomis whose name is in the message reply, in the order they are mentioned. Names are
+// matched as whole words, ignoring case. When no Nomi is mentioned, fallback picks the Nomis, and nobody replies if
+// it is nil
+func MentionedTurns(fallback TurnStrategy) TurnStrategy {
+	return TurnStrategyFunc(func(room Room, message string) []Nomi {
+		type mention struct {
+			nomi  Nomi
+			index int
+		}
+
+		var mentions []mention
+		for _, n := range room.Nomis {
+			if index := mentionIndex(message, n.Name); index >= 0 {
+				mentions = append(mentions, mention{n, index})
+			}
+		}
+		if len(mentions) == 0 {
+			if fallback == nil {
+				return nil
+			}
+			return fallback.Next(room, message)
+		}
+
+		slices.SortStableFunc(mentions, func(a, b mention) int {
+			return a.index - b.index
+		})
+		nomis := make([]Nomi, len(mentions))
+		for i, m := range mentions {
+			nomis[i] = m.nomi
+		}
+		return nomis
+	})
+}
+
+// mentionIndex returns where name is first found in message as a whole word, ignoring case, or -1
+func mentionIndex(message string, name string) int {
+	if name == "" {
+		return -1
+	}
+
+	re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(name))
+	for _, loc := range re.FindAllStringIndex(message, -1) {
+		before, _ := utf8.DecodeLastRuneInString(message[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(message[loc[1]:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return loc[0]
+		}
+	}
+
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// RoomReply is the reply of a Nomi in a RoomSession, or the error the request for it returned
+type RoomReply struct {
+	Nomi    Nomi
+	Message Message
+	Err     error
+}
+
+// RoomSession runs a conversation in a Room: after each message of the user, a TurnStrategy picks the Nomis replying,
+// and their replies are requested one at a time, since a Room refuses new requests with RoomNomiNotReadyForMessage
+// while a Nomi is replying. Calls to a RoomSession are serialized and safe for concurrent use
+type RoomSession struct {
+	client   API
+	room     Room
+	strategy TurnStrategy
+
+	// turn is held from the moment a message is sent until the last reply is received
+	turn sync.Mutex
+}
+
+// NewRoomSession creates a RoomSession in room. The replies are picked by strategy, or AllTurns if it is nil
+func NewRoomSession(client API, room Room, strategy TurnStrategy) *RoomSession {
+	if strategy == nil {
+		strategy = AllTurns()
+	}
+
+	return &RoomSession{client: client, room: room, strategy: strategy}
+}
+
+// Room returns the Room of the session
+func (s *RoomSession) Room() Room {
+	return s.room
+}
+
+// Send sends text in the Room and requests the replies of the Nomis picked by the strategy. The replies are streamed
+// on the returned channel, closed after the last one or when ctx is done. The next call to Send or Request waits
+// until then
+func (s *RoomSession) Send(ctx context.Context, text string) (SendRoomMessageResponse, <-chan RoomReply, error) {
+	s.turn.Lock()
+
+	res, err := s.client.SendRoomMessageContext(ctx, s.room.UUID.String(), SendRoomMessageBody{MessageText: text})
+	if err != nil {
+		s.turn.Unlock()
+		return res, nil, err
+	}
+
+	return res, s.request(ctx, s.strategy.Next(s.room, text)), nil
+}
+
+// Request requests a reply from each of nomis in turn, without sending a message first. The replies are streamed
+// like those of Send
+func (s *RoomSession) Request(ctx context.Context, nomis ...Nomi) <-chan RoomReply {
+	s.turn.Lock()
+	return s.request(ctx, nomis)
+}
+
+// request requests the replies of nomis and releases the turn after the last one
+func (s *RoomSession) request(ctx context.Context, nomis []Nomi) <-chan RoomReply {
+	replies := make(chan RoomReply, len(nomis))
+
+	go func() {
+		defer s.turn.Unlock()
+		defer close(replies)
+
+		for _, n := range nomis {
+			if ctx.Err() != nil {
+				return
+			}
+
+			res, err := s.client.RequestNomiRoomMessageContext(ctx, s.room.UUID.String(), RequestNomiRoomMessageBody{NomiUUID: n.UUID})
+			replies <- RoomReply{Nomi: n, Message: res.ReplyMessage, Err: err}
+		}
+	}()
+
+	return replies
+}
@@ -1,40 +1,23 @@
 package nomi
 
 import (
-	"bytes"
-	"encoding/json"
-	"github.com/google/uuid"
-	"io"
+	"context"
 	"net/http"
-	"net/url"
 )
 
 func (a api) GetRooms() (GetRoomsResponse, error) {
-	var res GetRoomsResponse
-
-	u, err := url.JoinPath(a.baseUrl, "rooms")
-	if err != nil {
-		return GetRoomsResponse{}, err
-	}
-
-	req, err := http.NewRequest(http.MethodGet, u, nil)
-	if err != nil {
-		return GetRoomsResponse{}, err
-	}
-	req.Header.Add("Authorization", a.apiKey)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return GetRoomsResponse{}, err
-	}
-	defer response.Body.Close()
+	return a.GetRoomsContext(context.Background())
+}
 
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return GetRoomsResponse{}, err
-	}
+func (a api) GetRoomsContext(ctx context.Context) (GetRoomsResponse, error) {
+	var res GetRoomsResponse
 
-	err = json.Unmarshal(b, &res)
+	err := a.do(ctx, operation{
+		name:   "GetRooms",
+		method: http.MethodGet,
+		path:   []string{"rooms"},
+		out:    &res,
+	})
 	if err != nil {
 		return GetRoomsResponse{}, err
 	}
@@ -43,44 +26,19 @@ func (a api) GetRooms() (GetRoomsResponse, error) {
 }
 
 func (a api) CreateRoom(body CreateRoomBody) (CreateRoomResponse, error) {
-	var res CreateRoomResponse
-
-	u, err := url.JoinPath(a.baseUrl, "rooms")
-	if err != nil {
-		return CreateRoomResponse{}, err
-	}
-
-	reqBody, err := json.Marshal(body)
-	if err != nil {
-		return CreateRoomResponse{}, err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, u, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return CreateRoomResponse{}, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", a.apiKey)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return CreateRoomResponse{}, err
-	}
-	defer response.Body.Close()
-
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return CreateRoomResponse{}, err
-	}
+	return a.CreateRoomContext(context.Background(), body)
+}
 
-	if response.StatusCode < 200 || response.StatusCode > 299 {
-		err = parseError(b)
-		if err != nil {
-			return CreateRoomResponse{}, err
-		}
-	}
+func (a api) CreateRoomContext(ctx context.Context, body CreateRoomBody) (CreateRoomResponse, error) {
+	var res CreateRoomResponse
 
-	err = json.Unmarshal(b, &res)
+	err := a.do(ctx, operation{
+		name:   "CreateRoom",
+		method: http.MethodPost,
+		path:   []string{"rooms"},
+		body:   body,
+		out:    &res,
+	})
 	if err != nil {
 		return CreateRoomResponse{}, err
 	}
@@ -89,43 +47,20 @@ func (a api) CreateRoom(body CreateRoomBody) (CreateRoomResponse, error) {
 }
 
 func (a api) GetRoom(roomID string) (GetRoomResponse, error) {
-	var res GetRoomResponse
-
-	id, err := uuid.Parse(roomID)
-	if err != nil {
-		return GetRoomResponse{}, InvalidRouteParams
-	}
-
-	u, err := url.JoinPath(a.baseUrl, "rooms", id.String())
-	if err != nil {
-		return GetRoomResponse{}, err
-	}
-
-	req, err := http.NewRequest(http.MethodGet, u, nil)
-	if err != nil {
-		return GetRoomResponse{}, err
-	}
-	req.Header.Add("Authorization", a.apiKey)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return GetRoomResponse{}, err
-	}
-	defer response.Body.Close()
-
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return GetRoomResponse{}, err
-	}
+	return a.GetRoomContext(context.Background(), roomID)
+}
 
-	if response.StatusCode < 200 || response.StatusCode > 299 {
-		err = parseError(b)
-		if err != nil {
-			return GetRoomResponse{}, err
-		}
-	}
+func (a api) GetRoomContext(ctx context.Context, roomID string) (GetRoomResponse, error) {
+	var res GetRoomResponse
 
-	err = json.Unmarshal(b, &res)
+	id, invalid := parseID(roomID)
+	err := a.do(ctx, operation{
+		name:    "GetRoom",
+		method:  http.MethodGet,
+		path:    []string{"rooms", id},
+		out:     &res,
+		invalid: invalid,
+	})
 	if err != nil {
 		return GetRoomResponse{}, err
 	}
@@ -134,49 +69,26 @@ func (a api) GetRoom(roomID string) (GetRoomResponse, error) {
 }
 
 func (a api) SendRoomMessage(roomID string, body SendRoomMessageBody) (SendRoomMessageResponse, error) {
-	var res SendRoomMessageResponse
-
-	id, err := uuid.Parse(roomID)
-	if err != nil {
-		return SendRoomMessageResponse{}, err
-	}
-
-	u, err := url.JoinPath(a.baseUrl, "rooms", id.String(), "chat")
-	if err != nil {
-		return SendRoomMessageResponse{}, err
-	}
-
-	reqBody, err := json.Marshal(body)
-	if err != nil {
-		return SendRoomMessageResponse{}, err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, u, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return SendRoomMessageResponse{}, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", a.apiKey)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return SendRoomMessageResponse{}, err
-	}
-	defer response.Body.Close()
+	return a.SendRoomMessageContext(context.Background(), roomID, body)
+}
 
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return SendRoomMessageResponse{}, err
-	}
+func (a api) SendRoomMessageContext(ctx context.Context, roomID string, body SendRoomMessageBody) (SendRoomMessageResponse, error) {
+	var res SendRoomMessageResponse
 
-	if response.StatusCode < 200 || response.StatusCode > 299 {
-		err = parseError(b)
-		if err != nil {
-			return SendRoomMessageResponse{}, err
-		}
+	id, invalid := parseID(roomID)
+	if invalid == nil {
+		invalid = body.Validate(a.maxMessageLength)
 	}
 
-	err = json.Unmarshal(b, &res)
+	err := a.do(ctx, operation{
+		name:    "SendRoomMessage",
+		method:  http.MethodPost,
+		path:    []string{"rooms", id, "chat"},
+		body:    body,
+		message: true,
+		out:     &res,
+		invalid: invalid,
+	})
 	if err != nil {
 		return SendRoomMessageResponse{}, err
 	}
@@ -185,49 +97,22 @@ func (a api) SendRoomMessage(roomID string, body SendRoomMessageBody) (SendRoomM
 }
 
 func (a api) RequestNomiRoomMessage(roomID string, body RequestNomiRoomMessageBody) (RequestNomiMessageResponse, error) {
-	var res RequestNomiMessageResponse
-
-	id, err := uuid.Parse(roomID)
-	if err != nil {
-		return RequestNomiMessageResponse{}, err
-	}
-
-	u, err := url.JoinPath(a.baseUrl, "rooms", id.String(), "chat", "request")
-	if err != nil {
-		return RequestNomiMessageResponse{}, err
-	}
-
-	reqBody, err := json.Marshal(body)
-	if err != nil {
-		return RequestNomiMessageResponse{}, err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, u, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return RequestNomiMessageResponse{}, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", a.apiKey)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return RequestNomiMessageResponse{}, err
-	}
-	defer response.Body.Close()
-
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return RequestNomiMessageResponse{}, err
-	}
+	return a.RequestNomiRoomMessageContext(context.Background(), roomID, body)
+}
 
-	if response.StatusCode < 200 || response.StatusCode > 299 {
-		err = parseError(b)
-		if err != nil {
-			return RequestNomiMessageResponse{}, err
-		}
-	}
+func (a api) RequestNomiRoomMessageContext(ctx context.Context, roomID string, body RequestNomiRoomMessageBody) (RequestNomiMessageResponse, error) {
+	var res RequestNomiMessageResponse
 
-	err = json.Unmarshal(b, &res)
+	id, invalid := parseID(roomID)
+	err := a.do(ctx, operation{
+		name:    "RequestNomiRoomMessage",
+		method:  http.MethodPost,
+		path:    []string{"rooms", id, "chat", "request"},
+		body:    body,
+		message: true,
+		out:     &res,
+		invalid: invalid,
+	})
 	if err != nil {
 		return RequestNomiMessageResponse{}, err
 	}
@@ -236,49 +121,21 @@ func (a api) RequestNomiRoomMessage(roomID string, body RequestNomiRoomMessageBo
 }
 
 func (a api) UpdateRoom(roomID string, body UpdateRoomBody) (UpdateRoomResponse, error) {
-	var res UpdateRoomResponse
-
-	id, err := uuid.Parse(roomID)
-	if err != nil {
-		return UpdateRoomResponse{}, err
-	}
-
-	u, err := url.JoinPath(a.baseUrl, "rooms", id.String())
-	if err != nil {
-		return UpdateRoomResponse{}, err
-	}
-
-	reqBody, err := json.Marshal(body)
-	if err != nil {
-		return UpdateRoomResponse{}, err
-	}
-
-	req, err := http.NewRequest(http.MethodPut, u, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return UpdateRoomResponse{}, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Authorization", a.apiKey)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return UpdateRoomResponse{}, err
-	}
-	defer response.Body.Close()
-
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return UpdateRoomResponse{}, err
-	}
+	return a.UpdateRoomContext(context.Background(), roomID, body)
+}
 
-	if response.StatusCode < 200 || response.StatusCode > 299 {
-		err = parseError(b)
-		if err != nil {
-			return UpdateRoomResponse{}, err
-		}
-	}
+func (a api) UpdateRoomContext(ctx context.Context, roomID string, body UpdateRoomBody) (UpdateRoomResponse, error) {
+	var res UpdateRoomResponse
 
-	err = json.Unmarshal(b, &res)
+	id, invalid := parseID(roomID)
+	err := a.do(ctx, operation{
+		name:    "UpdateRoom",
+		method:  http.MethodPut,
+		path:    []string{"rooms", id},
+		body:    body,
+		out:     &res,
+		invalid: invalid,
+	})
 	if err != nil {
 		return UpdateRoomResponse{}, err
 	}
@@ -287,36 +144,20 @@ func (a api) UpdateRoom(roomID string, body UpdateRoomBody) (UpdateRoomResponse,
 }
 
 func (a api) DeleteRoom(roomID string) (bool, error) {
-	id, err := uuid.Parse(roomID)
-	if err != nil {
-		return false, InvalidRouteParams
-	}
-
-	u, err := url.JoinPath(a.baseUrl, "rooms", id.String())
-	if err != nil {
-		return false, err
-	}
-
-	req, err := http.NewRequest(http.MethodDelete, u, nil)
-	if err != nil {
-		return false, err
-	}
-	req.Header.Add("Authorization", a.apiKey)
+	return a.DeleteRoomContext(context.Background(), roomID)
+}
 
-	response, err := http.DefaultClient.Do(req)
+func (a api) DeleteRoomContext(ctx context.Context, roomID string) (bool, error) {
+	id, invalid := parseID(roomID)
+	err := a.do(ctx, operation{
+		name:    "DeleteRoom",
+		method:  http.MethodDelete,
+		path:    []string{"rooms", id},
+		invalid: invalid,
+	})
 	if err != nil {
 		return false, err
 	}
-	defer response.Body.Close()
 
-	switch response.StatusCode {
-	case 204:
-		return true, nil
-	case 400:
-		return false, InvalidRouteParams
-	case 404:
-		return false, RoomNotFound
-	default:
-		return false, nil
-	}
+	return true, nil
 }
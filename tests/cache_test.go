@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"testing"
+	"time"
+)
+
+func TestCacheServesFreshResponses(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	room := server.AddRoom("Lounge", nomi.StatusDefault, alice.UUID)
+	client := nomi.WithCache(server.Client(), nomi.CacheOptions{TTL: time.Minute})
+
+	for range 3 {
+		if res, err := client.GetNomis(); err != nil || len(res.Nomis) != 1 {
+			t.Fatalf("Expected Alice, got %+v, %v", res, err)
+		}
+		if res, err := client.GetNomi(alice.UUID.String()); err != nil || res.Name != "Alice" {
+			t.Fatalf("Expected Alice, got %+v, %v", res, err)
+		}
+		if res, err := client.GetRoomContext(context.Background(), room.UUID.String()); err != nil || res.Name != "Lounge" {
+			t.Fatalf("Expected the Lounge, got %+v, %v", res, err)
+		}
+		if _, err := client.GetRooms(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, op := range []string{"GetNomis", "GetNomi", "GetRoom", "GetRooms"} {
+		if server.Calls(op) != 1 {
+			t.Errorf("Expected %s to be called once, got %d", op, server.Calls(op))
+		}
+	}
+
+	expiring := nomi.WithCache(server.Client(), nomi.CacheOptions{TTL: time.Millisecond})
+	expiring.GetNomis()
+	time.Sleep(5 * time.Millisecond)
+	expiring.GetNomis()
+	if server.Calls("GetNomis") != 3 {
+		t.Fatalf("Expected the response to expire, got %d calls", server.Calls("GetNomis"))
+	}
+}
+
+func TestCacheInvalidatesRooms(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	client := nomi.WithCache(server.Client(), nomi.CacheOptions{TTL: time.Minute})
+
+	rooms, _ := client.GetRooms()
+	if len(rooms.Rooms) != 0 {
+		t.Fatalf("Expected no Room, got %+v", rooms)
+	}
+
+	created, err := client.CreateRoom(nomi.CreateRoomBody{Name: "Lounge", NomiUUIDs: []uuid.UUID{alice.UUID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rooms, _ := client.GetRooms(); len(rooms.Rooms) != 1 {
+		t.Fatalf("Expected the new Room after CreateRoom, got %+v", rooms)
+	}
+	client.GetRoom(created.UUID.String())
+
+	name := "Reading room"
+	if _, err := client.UpdateRoom(created.UUID.String(), nomi.UpdateRoomBody{Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+	if room, _ := client.GetRoom(created.UUID.String()); room.Name != name {
+		t.Fatalf("Expected the new name after UpdateRoom, got %+v", room)
+	}
+	if rooms, _ := client.GetRooms(); rooms.Rooms[0].Name != name {
+		t.Fatalf("Expected the new name in the list after UpdateRoom, got %+v", rooms)
+	}
+
+	if _, err := client.DeleteRoom(created.UUID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetRoom(created.UUID.String()); !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected RoomNotFound after DeleteRoom, got %v", err)
+	}
+	if rooms, _ := client.GetRooms(); len(rooms.Rooms) != 0 {
+		t.Fatalf("Expected no Room after DeleteRoom, got %+v", rooms)
+	}
+}
+
+func TestCacheServesStaleResponsesOnErrors(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	client := nomi.WithCache(server.Client(), nomi.CacheOptions{TTL: time.Millisecond})
+
+	client.GetNomi(alice.UUID.String())
+	time.Sleep(5 * time.Millisecond)
+	server.FailNext("GetNomi", "NomiStillResponding")
+	if res, err := client.GetNomi(alice.UUID.String()); err != nil || res.Name != "Alice" {
+		t.Fatalf("Expected the stale response, got %+v, %v", res, err)
+	}
+
+	strict := nomi.WithCache(server.Client(), nomi.CacheOptions{TTL: time.Millisecond, MaxStale: -1})
+	strict.GetNomi(alice.UUID.String())
+	time.Sleep(5 * time.Millisecond)
+	server.FailNext("GetNomi", "NomiStillResponding")
+	if _, err := strict.GetNomi(alice.UUID.String()); !errors.Is(err, nomi.StillResponding) {
+		t.Fatalf("Expected the error without stale responses, got %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	server.FailNext("GetNomi", "Unauthorized")
+	if _, err := client.GetNomi(alice.UUID.String()); !unauthorized(err) {
+		t.Fatalf("Expected Unauthorized not to be hidden, got %v", err)
+	}
+
+	server.FailNext("GetNomi", "NomiNotFound")
+	if _, err := client.GetNomi(alice.UUID.String()); !errors.Is(err, nomi.NotFound) {
+		t.Fatalf("Expected NotFound not to be hidden, got %v", err)
+	}
+	if _, err := client.GetNomi(alice.UUID.String()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheLimitsStaleResponses(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	client := nomi.WithCache(server.Client(), nomi.CacheOptions{TTL: time.Millisecond, MaxStale: 5 * time.Millisecond})
+
+	client.GetNomi(alice.UUID.String())
+	time.Sleep(20 * time.Millisecond)
+	server.FailNext("GetNomi", "NomiStillResponding")
+	if _, err := client.GetNomi(alice.UUID.String()); !errors.Is(err, nomi.StillResponding) {
+		t.Fatalf("Expected the error past MaxStale, got %v", err)
+	}
+}
+
+func TestLRUCacheEvictsOldest(t *testing.T) {
+	cache := nomi.NewLRUCache(2)
+	ctx := context.Background()
+	cache.Set(ctx, "a", nomi.CacheEntry{Value: []byte("1")})
+	cache.Set(ctx, "b", nomi.CacheEntry{Value: []byte("2")})
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", nomi.CacheEntry{Value: []byte("3")})
+
+	if _, ok, _ := cache.Get(ctx, "b"); ok {
+		t.Fatal("Expected b to be evicted")
+	}
+	if entry, ok, _ := cache.Get(ctx, "a"); !ok || string(entry.Value) != "1" {
+		t.Fatal("Expected a to be kept")
+	}
+	cache.Delete(ctx, "a", "missing")
+	if cache.Len() != 1 {
+		t.Fatalf("Expected only c to be left, got %d entries", cache.Len())
+	}
+}
+
+func TestCacheSharedBetweenAccounts(t *testing.T) {
+	first := nomitest.NewServer()
+	defer first.Close()
+	first.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	second := nomitest.NewServer()
+	defer second.Close()
+	second.AddNomi("Bob", nomi.MALE, nomi.FRIEND)
+
+	backend := nomi.NewLRUCache(10)
+	alices := nomi.WithCache(nomi.NewClient("first-key", nomi.WithBaseURL(first.BaseURL())), nomi.CacheOptions{Backend: backend})
+	bobs := nomi.WithCache(nomi.NewClient("second-key", nomi.WithBaseURL(second.BaseURL())), nomi.CacheOptions{Backend: backend})
+
+	for _, c := range []struct {
+		client nomi.API
+		name   string
+	}{{alices, "Alice"}, {bobs, "Bob"}, {alices, "Alice"}, {bobs, "Bob"}} {
+		res, err := c.client.GetNomis()
+		if err != nil || len(res.Nomis) != 1 || res.Nomis[0].Name != c.name {
+			t.Fatalf("Expected %s, got %+v, %v", c.name, res, err)
+		}
+	}
+	if first.Calls("GetNomis") != 1 || second.Calls("GetNomis") != 1 || backend.Len() != 2 {
+		t.Fatalf("Expected each account to be cached apart, got %d and %d calls, %d entries",
+			first.Calls("GetNomis"), second.Calls("GetNomis"), backend.Len())
+	}
+
+	// decorators hide the client, so the namespace is given explicitly
+	wrapped := nomi.WithCache(nomi.WithNameResolution(second.Client(), time.Minute), nomi.CacheOptions{Backend: backend, Namespace: "bob"})
+	res, err := wrapped.GetNomis()
+	if err != nil || res.Nomis[0].Name != "Bob" || backend.Len() != 3 {
+		t.Fatalf("Expected Bob in a third entry, got %+v, %v, %d entries", res, err, backend.Len())
+	}
+}
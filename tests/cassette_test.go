@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/cassette"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordsAndReplays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	server := nomitest.NewServer()
+	server.APIKey = "secret-key"
+	bob := server.AddNomi("Bob", nomi.MALE, nomi.FRIEND)
+	server.Script(bob.UUID, "Recorded reply")
+
+	recorder, err := cassette.New(path, cassette.Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := nomi.NewClient("secret-key", nomi.WithBaseURL(server.BaseURL()), nomi.WithHTTPClient(recorder.Client()))
+
+	_, err = c.GetNomis()
+	if err != nil {
+		t.Fatalf("Could not list the Nomis while recording. Err: %s", err)
+	}
+	_, err = c.SendMessage(bob.UUID.String(), nomi.SendMessageBody{MessageText: "Hi"})
+	if err != nil {
+		t.Fatalf("Could not send a message while recording. Err: %s", err)
+	}
+	if unused := recorder.Unused(); len(unused) != 0 {
+		t.Fatalf("Expected no unused interaction while recording, got %d", len(unused))
+	}
+	recorder.SetMeta("nomi", bob.UUID.String())
+	err = recorder.Save()
+	if err != nil {
+		t.Fatalf("Could not save the cassette. Err: %s", err)
+	}
+	baseURL := server.BaseURL()
+	server.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "secret-key") {
+		t.Fatalf("Expected the Authorization header to be scrubbed from the cassette")
+	}
+
+	replayer, err := cassette.New(path, cassette.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayer.Meta("nomi") != bob.UUID.String() {
+		t.Fatalf("Expected the meta to be saved with the cassette, got %q", replayer.Meta("nomi"))
+	}
+	c = nomi.NewClient("another-key", nomi.WithBaseURL(baseURL), nomi.WithHTTPClient(replayer.Client()))
+
+	nomis, err := c.GetNomis()
+	if err != nil || len(nomis.Nomis) != 1 || nomis.Nomis[0].Name != "Bob" {
+		t.Fatalf("Expected the recorded Nomis, got %+v (err: %v)", nomis, err)
+	}
+	res, err := c.SendMessage(bob.UUID.String(), nomi.SendMessageBody{MessageText: "Hi"})
+	if err != nil || res.ReplyMessage.Text != "Recorded reply" {
+		t.Fatalf("Expected the recorded reply, got %+v (err: %v)", res, err)
+	}
+	if len(replayer.Unused()) != 0 {
+		t.Fatalf("Expected every interaction to be replayed, %d left", len(replayer.Unused()))
+	}
+
+	_, err = c.GetNomis()
+	if !cassette.IsUnmatched(err) {
+		t.Fatalf("Expected a request replayed twice to be unmatched, got %v", err)
+	}
+	_, err = c.SendMessage(uuid.NewString(), nomi.SendMessageBody{MessageText: "Something else"})
+	if !cassette.IsUnmatched(err) {
+		t.Fatalf("Expected an unrecorded request to be unmatched, got %v", err)
+	}
+}
+
+func TestCassetteComparesBodiesByValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorded := cassette.Cassette{Interactions: []cassette.Interaction{{
+		Request:  cassette.Request{Method: http.MethodPost, Path: "/v1/rooms", Body: `{"name":"Lounge","nomiUuids":["a","b"]}`},
+		Response: cassette.Response{StatusCode: http.StatusOK, Body: "{}"},
+	}}}
+	b, err := json.Marshal(recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := cassette.New(path, cassette.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := replayer.Client()
+
+	res, err := client.Post("http://nomi.test/v1/rooms", "application/json", strings.NewReader(`{"nomiUuids":["b","a"], "name":"Lounge"}`))
+	if err == nil {
+		res.Body.Close()
+	}
+	if !cassette.IsUnmatched(err) {
+		t.Fatalf("Expected a body with other values to be unmatched, got %v", err)
+	}
+
+	res, err = client.Post("http://nomi.test/v1/rooms", "application/json", strings.NewReader(`{ "nomiUuids": ["a", "b"], "name": "Lounge" }`))
+	if err != nil {
+		t.Fatalf("Expected a body with the keys in another order to match. Err: %s", err)
+	}
+	res.Body.Close()
+}
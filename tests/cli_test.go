@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/internal/cli"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs the nomi command against server and returns its exit code and outputs
+func runCLI(t *testing.T, server *nomitest.Server, args ...string) (int, string, string) {
+	t.Helper()
+	return runCLIWithInput(t, server, "", args...)
+}
+
+// runCLIWithInput runs the CLI like runCLI, with input as its standard input
+func runCLIWithInput(t *testing.T, server *nomitest.Server, input string, args ...string) (int, string, string) {
+	t.Helper()
+
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(config, []byte("api_key: cli-key\nbase_url: "+server.BaseURL()+"\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	c := &cli.CLI{
+		Stdin:  strings.NewReader(input),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Getenv: func(key string) string { return "" },
+	}
+	code := c.Run(append(args, "--config", config))
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLIListsNomisInEveryFormat(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+
+	code, stdout, stderr := runCLI(t, server, "nomis", "list")
+	if code != cli.ExitOK || !strings.Contains(stdout, "Alice") || !strings.HasPrefix(stdout, "UUID") {
+		t.Fatalf("Expected a table listing Alice, got code %d, %q %q", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCLI(t, server, "nomis", "list", "-o", "json")
+	var res nomi.GetNomisResponse
+	if code != cli.ExitOK || json.Unmarshal([]byte(stdout), &res) != nil || res.Nomis[0].Name != "Alice" {
+		t.Fatalf("Expected JSON listing Alice, got code %d, %q", code, stdout)
+	}
+
+	code, stdout, _ = runCLI(t, server, "nomis", "list", "--output", "yaml")
+	if code != cli.ExitOK || !strings.Contains(stdout, "name: Alice") || !strings.Contains(stdout, "relationshipType: Friend") {
+		t.Fatalf("Expected YAML with the JSON keys, got code %d, %q", code, stdout)
+	}
+}
+
+func TestCLIManagesRooms(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+
+	code, stdout, stderr := runCLI(t, server, "rooms", "create", "--name", "cli-room", "--nomi", alice.UUID.String(), "-o", "json")
+	var room nomi.Room
+	if code != cli.ExitOK || json.Unmarshal([]byte(stdout), &room) != nil || room.Name != "cli-room" {
+		t.Fatalf("Expected the created room, got code %d, %q %q", code, stdout, stderr)
+	}
+
+	code, _, stderr = runCLI(t, server, "rooms", "update", room.UUID.String(), "--note", "updated", "--backchanneling=true")
+	if code != cli.ExitOK || server.Rooms()[0].Note != "updated" || !server.Rooms()[0].BackchannelingEnabled {
+		t.Fatalf("Expected the room to be updated, got code %d, %q", code, stderr)
+	}
+
+	code, stdout, _ = runCLI(t, server, "rooms", "send", room.UUID.String(), "Hello", "everyone")
+	if code != cli.ExitOK || !strings.Contains(stdout, "Hello everyone") {
+		t.Fatalf("Expected the sent message, got code %d, %q", code, stdout)
+	}
+
+	code, stdout, _ = runCLI(t, server, "rooms", "request", room.UUID.String(), alice.UUID.String())
+	if code != cli.ExitOK || !strings.Contains(stdout, "Alice heard: Hello everyone") {
+		t.Fatalf("Expected Alice's reply, got code %d, %q", code, stdout)
+	}
+
+	code, _, _ = runCLI(t, server, "rooms", "delete", room.UUID.String())
+	if code != cli.ExitOK || len(server.Rooms()) != 0 {
+		t.Fatalf("Expected the room to be deleted, got code %d", code)
+	}
+}
+
+func TestCLIManagesRoomsByName(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	bob := server.AddNomi("Bob", nomi.MALE, nomi.FRIEND)
+
+	code, _, stderr := runCLI(t, server, "rooms", "create", "--name", "Lounge", "--nomi", "alice", "--nomi", bob.UUID.String())
+	if code != cli.ExitOK || len(server.Rooms()) != 1 || len(server.Rooms()[0].Nomis) != 2 {
+		t.Fatalf("Expected the room to be created with Alice and Bob, got code %d, %q", code, stderr)
+	}
+
+	code, _, stderr = runCLI(t, server, "rooms", "update", "Lounge", "--nomi", "Bob")
+	if nomis := server.Rooms()[0].Nomis; code != cli.ExitOK || len(nomis) != 1 || nomis[0].UUID != bob.UUID {
+		t.Fatalf("Expected Bob alone in the room, got code %d, %q", code, stderr)
+	}
+
+	code, stdout, _ := runCLI(t, server, "rooms", "request", "Lounge", "bob")
+	if code != cli.ExitOK || !strings.Contains(stdout, "Bob") {
+		t.Fatalf("Expected Bob's reply, got code %d, %q", code, stdout)
+	}
+	if code, _, _ := runCLI(t, server, "rooms", "request", "Lounge", "Alice"); code != cli.ExitNotFound {
+		t.Fatalf("Expected Alice not to be found in the room, got code %d", code)
+	}
+	if code, _, _ := runCLI(t, server, "rooms", "create", "--name", "Other", "--nomi", "Carol"); code != cli.ExitNotFound {
+		t.Fatalf("Expected an unknown Nomi to be reported, got code %d", code)
+	}
+}
+
+func TestCLIExitCodes(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+
+	if code, _, _ := runCLI(t, server, "rooms", "get", "5d7f9b1c-3c4e-4f6a-9a1b-2c3d4e5f6a7b"); code != cli.ExitNotFound {
+		t.Errorf("Expected exit code %d for a missing room, got %d", cli.ExitNotFound, code)
+	}
+	if code, _, _ := runCLI(t, server, "nomis", "get", "Nobody"); code != cli.ExitNotFound {
+		t.Errorf("Expected exit code %d for an unknown name, got %d", cli.ExitNotFound, code)
+	}
+	server.AddNomi("Twin", nomi.FEMALE, nomi.FRIEND)
+	server.AddNomi("Twin", nomi.MALE, nomi.FRIEND)
+	if code, _, _ := runCLI(t, server, "nomis", "get", "Twin"); code != cli.ExitInvalid {
+		t.Errorf("Expected exit code %d for an ambiguous name, got %d", cli.ExitInvalid, code)
+	}
+	if code, _, _ := runCLI(t, server, "nomis", "get"); code != cli.ExitUsage {
+		t.Errorf("Expected exit code %d for a missing argument, got %d", cli.ExitUsage, code)
+	}
+
+	server.FailNext("GetRooms", "LimitExceeded")
+	if code, _, _ := runCLI(t, server, "rooms", "list"); code != cli.ExitLimit {
+		t.Errorf("Expected exit code %d for LimitExceeded, got %d", cli.ExitLimit, code)
+	}
+
+	server.FailNext("GetNomis", "NoReply")
+	if code, _, _ := runCLI(t, server, "nomis", "list"); code != cli.ExitUnavailable {
+		t.Errorf("Expected exit code %d for NoReply, got %d", cli.ExitUnavailable, code)
+	}
+}
@@ -0,0 +1,262 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type endpoint struct {
+	name   string
+	method string
+	path   string
+	// hasBody is set for endpoints that send a JSON body
+	hasBody bool
+	call    func(c nomi.API, id string) error
+}
+
+var testID = uuid.MustParse("5d7f9b1c-3c4e-4f6a-9a1b-2c3d4e5f6a7b")
+
+// endpoints lists every operation of the API interface. The id is used for both Nomi and Room IDs
+func endpoints() []endpoint {
+	return []endpoint{
+		{"GetNomis", http.MethodGet, "/nomis", false, func(c nomi.API, id string) error {
+			_, err := c.GetNomis()
+			return err
+		}},
+		{"GetNomi", http.MethodGet, "/nomis/" + testID.String(), false, func(c nomi.API, id string) error {
+			_, err := c.GetNomi(id)
+			return err
+		}},
+		{"SendMessage", http.MethodPost, "/nomis/" + testID.String() + "/chat", true, func(c nomi.API, id string) error {
+			_, err := c.SendMessage(id, nomi.SendMessageBody{MessageText: "Hi"})
+			return err
+		}},
+		{"GetRooms", http.MethodGet, "/rooms", false, func(c nomi.API, id string) error {
+			_, err := c.GetRooms()
+			return err
+		}},
+		{"CreateRoom", http.MethodPost, "/rooms", true, func(c nomi.API, id string) error {
+			_, err := c.CreateRoom(nomi.CreateRoomBody{Name: "test", NomiUUIDs: []uuid.UUID{testID}})
+			return err
+		}},
+		{"GetRoom", http.MethodGet, "/rooms/" + testID.String(), false, func(c nomi.API, id string) error {
+			_, err := c.GetRoom(id)
+			return err
+		}},
+		{"SendRoomMessage", http.MethodPost, "/rooms/" + testID.String() + "/chat", true, func(c nomi.API, id string) error {
+			_, err := c.SendRoomMessage(id, nomi.SendRoomMessageBody{MessageText: "Hi"})
+			return err
+		}},
+		{"RequestNomiRoomMessage", http.MethodPost, "/rooms/" + testID.String() + "/chat/request", true, func(c nomi.API, id string) error {
+			_, err := c.RequestNomiRoomMessage(id, nomi.RequestNomiRoomMessageBody{NomiUUID: testID})
+			return err
+		}},
+		{"UpdateRoom", http.MethodPut, "/rooms/" + testID.String(), true, func(c nomi.API, id string) error {
+			_, err := c.UpdateRoom(id, nomi.UpdateRoomBody{})
+			return err
+		}},
+		{"DeleteRoom", http.MethodDelete, "/rooms/" + testID.String(), false, func(c nomi.API, id string) error {
+			_, err := c.DeleteRoom(id)
+			return err
+		}},
+	}
+}
+
+// newTestServer starts a server answering every request with status and body, and a client pointed at it
+func newTestServer(t *testing.T, status int, body string, onRequest func(r *http.Request), opts ...nomi.Option) nomi.API {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if onRequest != nil {
+			onRequest(r)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	opts = append([]nomi.Option{nomi.WithBaseURL(server.URL + "/v1/"), nomi.WithUserAgent("sdk-tests")}, opts...)
+	return nomi.NewClient("test-key", opts...)
+}
+
+func TestEndpointsBuildRequestsConsistently(t *testing.T) {
+	for _, e := range endpoints() {
+		t.Run(e.name, func(t *testing.T) {
+			var got *http.Request
+			var gotBody []byte
+			c := newTestServer(t, http.StatusOK, "{}", func(r *http.Request) {
+				got = r
+				gotBody, _ = io.ReadAll(r.Body)
+			})
+
+			err := e.call(c, testID.String())
+			if err != nil {
+				t.Fatalf("Unexpected error. Err: %s", err)
+			}
+
+			if got.Method != e.method {
+				t.Errorf("Expected method %s, got %s", e.method, got.Method)
+			}
+			if got.URL.Path != "/v1"+e.path {
+				t.Errorf("Expected path /v1%s, got %s", e.path, got.URL.Path)
+			}
+			if got.Header.Get("Authorization") != "test-key" {
+				t.Errorf("Expected the API key in the Authorization header, got %q", got.Header.Get("Authorization"))
+			}
+			if got.Header.Get("User-Agent") != "sdk-tests" {
+				t.Errorf("Expected the configured User-Agent, got %q", got.Header.Get("User-Agent"))
+			}
+
+			if e.hasBody {
+				if got.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("Expected a JSON Content-Type, got %q", got.Header.Get("Content-Type"))
+				}
+				if len(gotBody) == 0 {
+					t.Errorf("Expected a request body")
+				}
+			} else if len(gotBody) != 0 {
+				t.Errorf("Expected no request body, got %s", gotBody)
+			}
+		})
+	}
+}
+
+func TestEndpointsParseAPIErrors(t *testing.T) {
+	for _, e := range endpoints() {
+		t.Run(e.name, func(t *testing.T) {
+			c := newTestServer(t, http.StatusNotFound, `{"error":{"type":"RoomNotFound"}}`, nil)
+
+			err := e.call(c, testID.String())
+			if !errors.Is(err, nomi.RoomNotFound) {
+				t.Fatalf("Expected RoomNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEndpointsFailOnUnexpectedStatus(t *testing.T) {
+	for _, e := range endpoints() {
+		t.Run(e.name, func(t *testing.T) {
+			c := newTestServer(t, http.StatusInternalServerError, "upstream unavailable", nil)
+
+			err := e.call(c, testID.String())
+			if err == nil {
+				t.Fatalf("Expected an error for a 500 response")
+			}
+		})
+	}
+}
+
+func TestEndpointsRejectInvalidIDs(t *testing.T) {
+	for _, e := range endpoints() {
+		if e.name == "GetNomis" || e.name == "GetRooms" || e.name == "CreateRoom" {
+			continue
+		}
+
+		t.Run(e.name, func(t *testing.T) {
+			called := false
+			c := newTestServer(t, http.StatusOK, "{}", func(r *http.Request) {
+				called = true
+			})
+
+			err := e.call(c, "not-a-uuid")
+			if !errors.Is(err, nomi.InvalidRouteParams) {
+				t.Fatalf("Expected InvalidRouteParams, got %v", err)
+			}
+			if called {
+				t.Fatalf("Expected no request to be sent for an invalid ID")
+			}
+		})
+	}
+}
+
+func TestRequestsStopWhenContextIsDone(t *testing.T) {
+	received := make(chan struct{}, 1)
+	c := newTestServer(t, http.StatusOK, "{}", func(r *http.Request) {
+		received <- struct{}{}
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-received
+		cancel()
+	}()
+	if _, err := c.GetNomisContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetNomisContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// countingTransport counts the requests sent through it
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWithHeader(t *testing.T) {
+	var got http.Header
+	c := newTestServer(t, http.StatusOK, "{}", func(r *http.Request) {
+		got = r.Header
+	}, nomi.WithHeader("X-Request-Source", "tests"), nomi.WithHeader("Authorization", "someone-else"))
+
+	if _, err := c.GetNomis(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if got.Get("X-Request-Source") != "tests" {
+		t.Errorf("Expected the extra header to be sent, got %v", got)
+	}
+	if auth := got.Values("Authorization"); len(auth) != 1 || auth[0] != "test-key" {
+		t.Errorf("Expected WithHeader not to override the API key, got %v", auth)
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	transport := &countingTransport{}
+	c := newTestServer(t, http.StatusOK, "{}", nil, nomi.WithHTTPClient(&http.Client{Transport: transport}))
+
+	if _, err := c.GetNomis(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if transport.requests != 1 {
+		t.Fatalf("Expected the request to go through the given client, got %d requests", transport.requests)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	transport := &countingTransport{}
+	httpClient := &http.Client{Transport: transport, Timeout: time.Minute}
+	c := newTestServer(t, http.StatusOK, "{}", func(r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}, nomi.WithHTTPClient(httpClient), nomi.WithTimeout(50*time.Millisecond))
+
+	if _, err := c.GetNomis(); err == nil {
+		t.Fatalf("Expected the request to time out")
+	}
+	if httpClient.Timeout != time.Minute {
+		t.Errorf("Expected WithTimeout not to change the given client, got a timeout of %s", httpClient.Timeout)
+	}
+	if transport.requests != 1 {
+		t.Errorf("Expected the request to go through the transport of the given client, got %d requests", transport.requests)
+	}
+}
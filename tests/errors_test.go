@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/vhalmd/nomi-go-sdk"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorKeepsResponseDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"type":"InvalidBody","issues":[{"code":"invalid_type","expected":"string","received":"undefined","path":["messageText"],"message":"Required"}]}}`))
+	}))
+	defer server.Close()
+
+	c := nomi.NewClient("test-key", nomi.WithBaseURL(server.URL))
+	_, err := c.SendMessage(testID.String(), nomi.SendMessageBody{})
+
+	if !errors.Is(err, nomi.InvalidBody) {
+		t.Fatalf("Expected InvalidBody, got %v", err)
+	}
+
+	var apiErr *nomi.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected a *nomi.Error, got %T", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", apiErr.StatusCode)
+	}
+	if apiErr.Type != "InvalidBody" {
+		t.Errorf("Expected type InvalidBody, got %s", apiErr.Type)
+	}
+	if apiErr.Method != http.MethodPost || !strings.HasSuffix(apiErr.URL, "/chat") {
+		t.Errorf("Expected the failed request to be recorded, got %s %s", apiErr.Method, apiErr.URL)
+	}
+	if apiErr.RequestID != "req-123" {
+		t.Errorf("Expected request ID req-123, got %q", apiErr.RequestID)
+	}
+	if len(apiErr.Issues) != 1 || apiErr.Issues[0].Message != "Required" || apiErr.Issues[0].Path[0] != "messageText" {
+		t.Errorf("Expected the issue to be decoded, got %+v", apiErr.Issues)
+	}
+	if len(apiErr.Body) == 0 {
+		t.Errorf("Expected the raw body to be kept")
+	}
+}
+
+func TestErrorWithUnknownType(t *testing.T) {
+	c := newTestServer(t, http.StatusTeapot, `{"error":{"type":"SomethingNew"}}`, nil)
+	_, err := c.GetNomis()
+
+	if !errors.Is(err, &nomi.Error{Type: "SomethingNew"}) {
+		t.Fatalf("Expected the error to match its type, got %v", err)
+	}
+	if errors.Is(err, nomi.NotFound) {
+		t.Fatalf("Expected an unknown type not to match a sentinel error")
+	}
+}
+
+func TestErrorWithUnparseableBody(t *testing.T) {
+	c := newTestServer(t, http.StatusBadGateway, "<html>bad gateway</html>", nil)
+	_, err := c.GetRooms()
+
+	var apiErr *nomi.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected a *nomi.Error, got %T", err)
+	}
+	if apiErr.StatusCode != http.StatusBadGateway || apiErr.Type != "" {
+		t.Fatalf("Expected status 502 without a type, got %d %q", apiErr.StatusCode, apiErr.Type)
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	cases := map[string]error{
+		"":                           nil,
+		"NomiNotFound":               &nomi.Error{Type: "NomiNotFound"},
+		"SomethingNew":               &nomi.Error{Type: "SomethingNew"},
+		"UnexpectedResponse":         &nomi.Error{StatusCode: http.StatusBadGateway},
+		"LimitExceeded":              &nomi.QuotaExceededError{},
+		"MessageLengthLimitExceeded": fmt.Errorf("message 2: %w", nomi.MessageLengthLimitExceeded),
+		"DeadlineExceeded":           fmt.Errorf("request aborted: %w", context.DeadlineExceeded),
+		"Canceled":                   context.Canceled,
+		"Other":                      errors.New("boom"),
+	}
+
+	for expected, err := range cases {
+		if got := nomi.ErrorType(err); got != expected {
+			t.Errorf("Expected %q for %v, got %q", expected, err, got)
+		}
+	}
+}
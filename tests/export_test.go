@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/export"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportRoom returns a Room with Alice and Bob and a conversation spanning two days, out of order
+func exportRoom() export.Conversation {
+	alice := nomi.Nomi{UUID: uuid.New(), Name: "Alice"}
+	bob := nomi.Nomi{UUID: uuid.New(), Name: "Bob"}
+	room := nomi.Room{UUID: uuid.New(), Name: "Book club", Nomis: []nomi.Nomi{alice, bob}}
+	day := time.Date(2025, 3, 1, 21, 30, 0, 0, time.UTC)
+
+	return export.Conversation{
+		Room: &room,
+		Entries: []nomi.HistoryEntry{
+			{RoomUUID: room.UUID, NomiUUID: bob.UUID, Direction: nomi.DirectionReply, Message: nomi.Message{Text: "See you *tomorrow*", Sent: day.Add(5 * time.Minute)}},
+			{RoomUUID: room.UUID, Direction: nomi.DirectionSent, Message: nomi.Message{Text: "Good night <all>", Sent: day}},
+			{RoomUUID: room.UUID, NomiUUID: alice.UUID, Direction: nomi.DirectionReply, Message: nomi.Message{Text: "Good morning", Sent: day.Add(12 * time.Hour)}},
+			{RoomUUID: room.UUID, NomiUUID: uuid.Nil, Direction: nomi.DirectionReply, Message: nomi.Message{Text: "Who am I?", Sent: day.Add(13 * time.Hour)}},
+		},
+	}
+}
+
+func exportString(t *testing.T, format export.Format, c export.Conversation, opts export.Options) string {
+	t.Helper()
+
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	var b bytes.Buffer
+	err := export.Write(&b, format, c, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func TestExportMarkdown(t *testing.T) {
+	got := exportString(t, export.Markdown, exportRoom(), export.Options{})
+	want := "# Book club\n\n" +
+		"## Saturday, March 1, 2025\n\n" +
+		"**You** (21:30): Good night \\<all\\>\n\n" +
+		"**Bob** (21:35): See you \\*tomorrow\\*\n\n" +
+		"## Sunday, March 2, 2025\n\n" +
+		"**Alice** (09:30): Good morning\n\n" +
+		"**Nomi 00000000-0000-0000-0000-000000000000** (10:30): Who am I?\n"
+	if got != want {
+		t.Fatalf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestExportText(t *testing.T) {
+	c := exportRoom()
+	c.Title = "Club"
+	got := exportString(t, export.Text, c, export.Options{
+		Since:    time.Date(2025, 3, 1, 21, 31, 0, 0, time.UTC),
+		Until:    time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC),
+		UserName: "Me",
+	})
+	want := "Club\n\n== Saturday, March 1, 2025 ==\n[21:35] Bob: See you *tomorrow*\n\n== Sunday, March 2, 2025 ==\n[09:30] Alice: Good morning\n"
+	if got != want {
+		t.Fatalf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestExportGroupsByDayInLocation(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	got := exportString(t, export.Text, exportRoom(), export.Options{Location: tokyo})
+	if strings.Count(got, "==") != 2 || !strings.Contains(got, "== Sunday, March 2, 2025 ==\n[06:30] You") {
+		t.Fatalf("Expected every message on the 2nd of March in Tokyo, got\n%s", got)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	c := exportRoom()
+	got := exportString(t, export.JSON, c, export.Options{})
+
+	var res struct {
+		Title    string    `json:"title"`
+		RoomUUID uuid.UUID `json:"roomUuid"`
+		Days     []struct {
+			Date     string `json:"date"`
+			Messages []struct {
+				Speaker   string         `json:"speaker"`
+				Direction nomi.Direction `json:"direction"`
+				Text      string         `json:"text"`
+			} `json:"messages"`
+		} `json:"days"`
+	}
+	err := json.Unmarshal([]byte(got), &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Title != "Book club" || res.RoomUUID != c.Room.UUID || len(res.Days) != 2 || res.Days[1].Date != "2025-03-02" {
+		t.Fatalf("Expected two days of the Book club, got %+v", res)
+	}
+	if m := res.Days[0].Messages[1]; m.Speaker != "Bob" || m.Direction != nomi.DirectionReply || m.Text != "See you *tomorrow*" {
+		t.Fatalf("Expected the reply of Bob, got %+v", m)
+	}
+}
+
+func TestExportHTML(t *testing.T) {
+	alice := nomi.Nomi{UUID: uuid.New(), Name: "Alice"}
+	c := export.Conversation{
+		Nomi: &alice,
+		Entries: []nomi.HistoryEntry{
+			{NomiUUID: alice.UUID, Direction: nomi.DirectionSent, Message: nomi.Message{Text: "<script>alert(1)</script>", Sent: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)}},
+			{NomiUUID: alice.UUID, Direction: nomi.DirectionReply, Message: nomi.Message{Text: "Nice try", Sent: time.Date(2025, 3, 1, 9, 1, 0, 0, time.UTC)}},
+		},
+	}
+	got := exportString(t, export.HTML, c, export.Options{})
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<title>Conversation with Alice</title>",
+		"<h2>Saturday, March 1, 2025</h2>",
+		`<div class="message reply"><span class="speaker">Alice</span>`,
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		"<style>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected the page to contain %q, got\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<script>") {
+		t.Fatalf("Expected the messages to be escaped, got\n%s", got)
+	}
+}
+
+func TestExportParseFormat(t *testing.T) {
+	for name, want := range map[string]export.Format{"md": export.Markdown, "HTML": export.HTML, "json": export.JSON, "txt": export.Text} {
+		got, err := export.ParseFormat(name)
+		if err != nil || got != want {
+			t.Errorf("Expected %s to be %s, got %s, %v", name, want, got, err)
+		}
+	}
+	if _, err := export.ParseFormat("pdf"); err == nil {
+		t.Fatal("Expected pdf to be refused")
+	}
+}
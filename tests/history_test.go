@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	_ "modernc.org/sqlite"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// historyStores returns every HistoryStore implementation, empty
+func historyStores(t *testing.T) map[string]nomi.HistoryStore {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	sqlite, err := nomi.NewSQLiteHistory(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]nomi.HistoryStore{
+		"memory": nomi.NewMemoryHistory(),
+		"jsonl":  nomi.NewJSONLHistory(filepath.Join(t.TempDir(), "history.jsonl")),
+		"sqlite": sqlite,
+	}
+}
+
+func TestHistorySavesConversations(t *testing.T) {
+	for name, store := range historyStores(t) {
+		t.Run(name, func(t *testing.T) {
+			server := nomitest.NewServer()
+			defer server.Close()
+			alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+			bob := server.AddNomi("Bob", nomi.MALE, nomi.FRIEND)
+			room := server.AddRoom("Book club", nomi.StatusDefault, alice.UUID, bob.UUID)
+			client := nomi.WithHistory(server.Client(), store)
+			ctx := context.Background()
+
+			if _, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "Hello Alice"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.SendRoomMessageContext(ctx, room.UUID.String(), nomi.SendRoomMessageBody{MessageText: "Hello club"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.RequestNomiRoomMessage(room.UUID.String(), nomi.RequestNomiRoomMessageBody{NomiUUID: bob.UUID}); err != nil {
+				t.Fatal(err)
+			}
+			server.FailNext("SendMessage", "NoReply")
+			if _, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "Lost"}); !errors.Is(err, nomi.NoReply) {
+				t.Fatalf("Expected NoReply, got %v", err)
+			}
+
+			all, err := store.Query(ctx, nomi.HistoryQuery{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 4 {
+				t.Fatalf("Expected 4 entries, got %+v", all)
+			}
+			if all[0].Direction != nomi.DirectionSent || all[0].NomiUUID != alice.UUID || all[0].Message.Text != "Hello Alice" {
+				t.Fatalf("Expected the message sent to Alice first, got %+v", all[0])
+			}
+			if all[3].Direction != nomi.DirectionReply || all[3].NomiUUID != bob.UUID || all[3].RoomUUID != room.UUID {
+				t.Fatalf("Expected the reply of Bob in the Room last, got %+v", all[3])
+			}
+
+			byNomi, _ := store.Query(ctx, nomi.HistoryQuery{NomiUUID: alice.UUID})
+			if len(byNomi) != 2 || byNomi[1].Message.Text != "Alice heard: Hello Alice" {
+				t.Fatalf("Expected the chat with Alice, got %+v", byNomi)
+			}
+
+			byRoom, _ := store.Query(ctx, nomi.HistoryQuery{RoomUUID: room.UUID})
+			if len(byRoom) != 2 || byRoom[0].Message.Text != "Hello club" {
+				t.Fatalf("Expected the Room conversation, got %+v", byRoom)
+			}
+
+			byText, _ := store.Query(ctx, nomi.HistoryQuery{Text: "BOB HEARD"})
+			if len(byText) != 1 || byText[0].NomiUUID != bob.UUID {
+				t.Fatalf("Expected the text search to ignore case, got %+v", byText)
+			}
+
+			last, _ := store.Query(ctx, nomi.HistoryQuery{Limit: 1})
+			if len(last) != 1 || last[0].NomiUUID != bob.UUID {
+				t.Fatalf("Expected the most recent entry, got %+v", last)
+			}
+		})
+	}
+}
+
+func TestHistoryMatchesUnicodeText(t *testing.T) {
+	for name, store := range historyStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+			for i, text := range []string{"Bonne journée à l'ÉCOLE", "Rien", "L'école est finie"} {
+				entry := nomi.HistoryEntry{NomiUUID: testID, Direction: nomi.DirectionSent,
+					Message: nomi.Message{UUID: uuid.New(), Text: text, Sent: start.Add(time.Duration(i) * time.Minute)}}
+				if err := store.Append(ctx, entry); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			found, err := store.Query(ctx, nomi.HistoryQuery{Text: "école"})
+			if err != nil || len(found) != 2 || found[0].Message.Text != "Bonne journée à l'ÉCOLE" {
+				t.Fatalf("Expected the text search to ignore the case of any letter, got %+v, %v", found, err)
+			}
+			last, err := store.Query(ctx, nomi.HistoryQuery{Text: "ÉCOLE", Limit: 1})
+			if err != nil || len(last) != 1 || last[0].Message.Text != "L'école est finie" {
+				t.Fatalf("Expected the most recent match, got %+v, %v", last, err)
+			}
+		})
+	}
+}
+
+func TestHistoryQueriesByTimeRange(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	nomiID := uuid.New()
+
+	for name, store := range historyStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i := range 5 {
+				err := store.Append(ctx, nomi.HistoryEntry{
+					NomiUUID:  nomiID,
+					Direction: nomi.DirectionSent,
+					Message:   nomi.Message{UUID: uuid.New(), Text: "message", Sent: start.Add(time.Duration(i) * time.Hour)},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			entries, err := store.Query(ctx, nomi.HistoryQuery{Since: start.Add(time.Hour), Until: start.Add(3 * time.Hour)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 || !entries[0].Message.Sent.Equal(start.Add(time.Hour)) || !entries[1].Message.Sent.Equal(start.Add(2*time.Hour)) {
+				t.Fatalf("Expected the messages of the second and third hours, got %+v", entries)
+			}
+
+			entries, _ = store.Query(ctx, nomi.HistoryQuery{NomiUUID: uuid.New()})
+			if len(entries) != 0 {
+				t.Fatalf("Expected no message for another Nomi, got %+v", entries)
+			}
+		})
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Append(ctx context.Context, entries ...nomi.HistoryEntry) error {
+	return errors.New("disk full")
+}
+
+func (failingStore) Query(ctx context.Context, q nomi.HistoryQuery) ([]nomi.HistoryEntry, error) {
+	return nil, nil
+}
+
+func TestHistoryReturnsResponseWhenSavingFails(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	client := nomi.WithHistory(server.Client(), failingStore{})
+
+	res, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "Hi"})
+	var historyErr *nomi.HistoryError
+	if !errors.As(err, &historyErr) || res.ReplyMessage.Text == "" {
+		t.Fatalf("Expected the reply and a HistoryError, got %+v, %v", res, err)
+	}
+}
+
+func TestHistoryWithNameResolution(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	room := server.AddRoom("Book club", nomi.StatusDefault, alice.UUID)
+	store := nomi.NewMemoryHistory()
+	ctx := context.Background()
+
+	client := nomi.WithNameResolution(nomi.WithHistory(server.Client(), store), time.Minute)
+	if _, err := client.SendMessage("alice", nomi.SendMessageBody{MessageText: "Hello Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SendRoomMessage("Book club", nomi.SendRoomMessageBody{MessageText: "Hello club"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := store.Query(ctx, nomi.HistoryQuery{NomiUUID: alice.UUID}); len(entries) != 2 {
+		t.Fatalf("Expected the chat with Alice to be saved under her UUID, got %+v", entries)
+	}
+	if entries, _ := store.Query(ctx, nomi.HistoryQuery{RoomUUID: room.UUID}); len(entries) != 1 {
+		t.Fatalf("Expected the Room message to be saved under its UUID, got %+v", entries)
+	}
+
+	// resolving names after the history client leaves it with names it cannot save
+	wrong := nomi.WithHistory(nomi.WithNameResolution(server.Client(), time.Minute), store)
+	res, err := wrong.SendMessage("Alice", nomi.SendMessageBody{MessageText: "Unsaved"})
+	var historyErr *nomi.HistoryError
+	if !errors.As(err, &historyErr) || res.ReplyMessage.Text != "Alice heard: Unsaved" {
+		t.Fatalf("Expected the reply with a *HistoryError, got %+v, %v", res, err)
+	}
+	if entries, _ := store.Query(ctx, nomi.HistoryQuery{Text: "Unsaved"}); len(entries) != 0 {
+		t.Fatalf("Expected nothing to be saved, got %+v", entries)
+	}
+}
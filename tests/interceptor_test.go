@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type interceptorKey struct{}
+
+func unauthorized(err error) bool {
+	var apiErr *nomi.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+func TestInterceptorsSeeCalls(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+
+	var events []string
+	var sent, received *nomi.Call
+	record := func(name string) nomi.Interceptor {
+		return nomi.Interceptor{
+			BeforeSend: func(ctx context.Context, call *nomi.Call) (context.Context, error) {
+				events = append(events, name+" before "+call.Operation)
+				sent = call
+				return context.WithValue(ctx, interceptorKey{}, name), nil
+			},
+			AfterReceive: func(ctx context.Context, call *nomi.Call) {
+				events = append(events, name+" after "+call.Operation+" in "+ctx.Value(interceptorKey{}).(string))
+				received = call
+			},
+			OnError: func(ctx context.Context, call *nomi.Call, err error) {
+				events = append(events, name+" error "+call.Operation)
+			},
+		}
+	}
+
+	client := server.Client(nomi.WithInterceptors(record("first"), record("second")))
+	_, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "Hello"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{
+		"first before SendMessage",
+		"second before SendMessage",
+		"second after SendMessage in second",
+		"first after SendMessage in second",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("Expected hooks %v, got %v", expected, events)
+	}
+	if body, ok := sent.Body.(nomi.SendMessageBody); !ok || body.MessageText != "Hello" || sent.Method != http.MethodPost {
+		t.Fatalf("Expected the request body to be seen, got %+v", sent)
+	}
+	res, ok := received.Result.(*nomi.SendMessageResponse)
+	if !ok || res.ReplyMessage.Text != "Alice heard: Hello" {
+		t.Fatalf("Expected the decoded response to be seen, got %+v", received.Result)
+	}
+	if received.StatusCode != http.StatusOK || received.Attempts != 1 || received.Duration <= 0 {
+		t.Fatalf("Unexpected call %+v", received)
+	}
+}
+
+func TestInterceptorsOnError(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	for range 2 {
+		server.FailNext("GetNomis", "NomiStillResponding")
+	}
+
+	var failed *nomi.Call
+	var failure error
+	client := server.Client(nomi.WithRetry(fastRetryPolicy()), nomi.WithInterceptors(nomi.Interceptor{
+		OnError: func(ctx context.Context, call *nomi.Call, err error) {
+			failed, failure = call, err
+		},
+	}))
+
+	if _, err := client.GetNomis(); err != nil || failed != nil {
+		t.Fatalf("Expected the retries to hide the errors, got %v, %+v", err, failed)
+	}
+
+	server.Fail("GetNomis", "Unauthorized")
+	_, err := client.GetNomis()
+	if !unauthorized(err) || !unauthorized(failure) {
+		t.Fatalf("Expected OnError to get Unauthorized, got %v, %v", err, failure)
+	}
+	if failed.Operation != "GetNomis" || failed.StatusCode != http.StatusUnauthorized || failed.Result != nil {
+		t.Fatalf("Unexpected call %+v", failed)
+	}
+}
+
+func TestInterceptorsSeeRejectedCalls(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+
+	var failures []error
+	var calls []*nomi.Call
+	client := server.Client(nomi.WithPlan(nomi.PlanFree), nomi.WithInterceptors(nomi.Interceptor{
+		OnError: func(ctx context.Context, call *nomi.Call, err error) {
+			if call.Attempts != 0 {
+				t.Errorf("Expected no request to be sent, got %+v", call)
+			}
+			failures = append(failures, err)
+			calls = append(calls, call)
+		},
+	}))
+
+	if _, err := client.GetNomi("alice"); !errors.Is(err, nomi.InvalidRouteParams) {
+		t.Fatalf("Expected InvalidRouteParams, got %v", err)
+	}
+	long := nomi.SendMessageBody{MessageText: strings.Repeat("a", nomi.FreeMaxMessageLength+1)}
+	if _, err := client.SendMessage(alice.UUID.String(), long); !errors.Is(err, nomi.MessageLengthLimitExceeded) {
+		t.Fatalf("Expected MessageLengthLimitExceeded, got %v", err)
+	}
+
+	if len(failures) != 2 || !errors.Is(failures[0], nomi.InvalidRouteParams) || !errors.Is(failures[1], nomi.MessageLengthLimitExceeded) {
+		t.Fatalf("Expected OnError to see the rejected calls, got %v", failures)
+	}
+	if !strings.HasSuffix(calls[0].URL, "/nomis/alice") || calls[0].NomiID != "" {
+		t.Errorf("Expected the URL to show the rejected ID, got %+v", calls[0])
+	}
+	if !strings.HasSuffix(calls[1].URL, "/nomis/"+alice.UUID.String()+"/chat") || calls[1].NomiID != alice.UUID.String() {
+		t.Errorf("Expected the URL and the ID of the Nomi, got %+v", calls[1])
+	}
+	if calls := server.Calls("GetNomi") + server.Calls("SendMessage"); calls != 0 {
+		t.Fatalf("Expected no request to reach the server, got %d", calls)
+	}
+}
+
+func TestInterceptorsMutateHeaders(t *testing.T) {
+	server := nomitest.NewServer()
+	server.APIKey = "fresh-key"
+	defer server.Close()
+
+	key := "stale-key"
+	client := nomi.NewClient("stale-key", nomi.WithBaseURL(server.BaseURL()), nomi.WithInterceptors(nomi.Interceptor{
+		BeforeSend: func(ctx context.Context, call *nomi.Call) (context.Context, error) {
+			call.Header.Set("Authorization", key)
+			return nil, nil
+		},
+	}))
+
+	if _, err := client.GetNomis(); !unauthorized(err) {
+		t.Fatalf("Expected the stale key to be refused, got %v", err)
+	}
+	key = "fresh-key"
+	if _, err := client.GetNomis(); err != nil {
+		t.Fatalf("Expected the refreshed key to be accepted, got %v", err)
+	}
+}
+
+func TestInterceptorsAbortCalls(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+
+	refused := errors.New("refused")
+	var events []string
+	client := server.Client(nomi.WithInterceptors(
+		nomi.Interceptor{
+			BeforeSend: func(ctx context.Context, call *nomi.Call) (context.Context, error) {
+				events = append(events, "first before")
+				return nil, nil
+			},
+			OnError: func(ctx context.Context, call *nomi.Call, err error) {
+				events = append(events, "first error")
+			},
+		},
+		nomi.Interceptor{
+			BeforeSend: func(ctx context.Context, call *nomi.Call) (context.Context, error) {
+				return nil, refused
+			},
+			OnError: func(ctx context.Context, call *nomi.Call, err error) {
+				events = append(events, "second error")
+			},
+		},
+	))
+
+	_, err := client.GetRooms()
+	if !errors.Is(err, refused) || server.Calls("GetRooms") != 0 {
+		t.Fatalf("Expected the call to be aborted, got %v, %d calls", err, server.Calls("GetRooms"))
+	}
+	if !reflect.DeepEqual(events, []string{"first before", "first error"}) {
+		t.Fatalf("Unexpected hooks %v", events)
+	}
+}
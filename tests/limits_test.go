@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomimock"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDailyQuotaRefusesLocally(t *testing.T) {
+	calls := 0
+	c := newTestServer(t, http.StatusOK, "{}", func(r *http.Request) {
+		calls++
+	}, nomi.WithDailyQuota(2))
+
+	for i := 0; i < 2; i++ {
+		_, err := c.SendMessage(testID.String(), nomi.SendMessageBody{MessageText: "Hi"})
+		if err != nil {
+			t.Fatalf("Expected message %d to be sent. Err: %s", i+1, err)
+		}
+	}
+
+	_, err := c.SendRoomMessage(testID.String(), nomi.SendRoomMessageBody{MessageText: "Hi"})
+	if !errors.Is(err, nomi.LimitExceeded) {
+		t.Fatalf("Expected LimitExceeded, got %v", err)
+	}
+
+	var quotaErr *nomi.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("Expected a *nomi.QuotaExceededError, got %T", err)
+	}
+	if quotaErr.ResetIn() <= 0 || quotaErr.ResetIn() > 24*time.Hour {
+		t.Errorf("Expected the quota to reset within a day, got %s", quotaErr.ResetIn())
+	}
+	if calls != 2 {
+		t.Errorf("Expected the third message not to reach the server, got %d requests", calls)
+	}
+
+	stats, ok := nomi.QuotaOf(c)
+	if !ok || stats.Limit != 2 || stats.Used != 2 || stats.Remaining != 0 {
+		t.Errorf("Unexpected quota stats %+v", stats)
+	}
+
+	_, err = c.GetNomis()
+	if err != nil {
+		t.Errorf("Expected reads to ignore the message quota. Err: %s", err)
+	}
+}
+
+func TestDailyQuotaIgnoresFailedMessages(t *testing.T) {
+	c := newTestServer(t, http.StatusConflict, `{"error":{"type":"NomiStillResponding"}}`, nil, nomi.WithDailyQuota(5))
+
+	_, _ = c.SendMessage(testID.String(), nomi.SendMessageBody{MessageText: "Hi"})
+
+	if used := c.(nomi.QuotaReporter).Quota().Used; used != 0 {
+		t.Fatalf("Expected a failed message not to be counted, got %d used", used)
+	}
+}
+
+func TestDailyQuotaFollowsServer(t *testing.T) {
+	c := newTestServer(t, http.StatusForbidden, `{"error":{"type":"LimitExceeded"}}`, nil, nomi.WithDailyQuota(50))
+
+	_, _ = c.SendMessage(testID.String(), nomi.SendMessageBody{MessageText: "Hi"})
+
+	if remaining := c.(nomi.QuotaReporter).Quota().Remaining; remaining != 0 {
+		t.Fatalf("Expected the quota to be exhausted after LimitExceeded, got %d remaining", remaining)
+	}
+}
+
+func TestRateLimitWaitsForTokens(t *testing.T) {
+	c := newTestServer(t, http.StatusOK, "{}", nil, nomi.WithRateLimit(20, 1))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := c.GetNomis()
+		if err != nil {
+			t.Fatalf("Unexpected error. Err: %s", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("Expected the requests to be spread over at least 100ms, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.GetNomisContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected waiting for a token to stop with the context, got %v", err)
+	}
+}
+
+func TestQuotaThroughDecorators(t *testing.T) {
+	c := nomi.WithCache(nomi.WithNameResolution(newTestServer(t, http.StatusOK, "{}", nil, nomi.WithDailyQuota(3)), time.Minute), nomi.CacheOptions{})
+
+	_, _ = c.SendMessage(testID.String(), nomi.SendMessageBody{MessageText: "Hi"})
+
+	if stats, ok := nomi.QuotaOf(c); !ok || stats.Used != 1 || stats.Remaining != 2 {
+		t.Fatalf("Expected the decorators to report the quota of the client, got %+v, %t", stats, ok)
+	}
+	if _, ok := nomi.QuotaOf(&nomimock.API{}); ok {
+		t.Fatalf("Expected a mock not to report a quota")
+	}
+	decorated := nomi.WithHistory(nomi.WithCache(&nomimock.API{}, nomi.CacheOptions{}), nomi.NewMemoryHistory())
+	if _, ok := nomi.QuotaOf(nomi.WithNameResolution(decorated, time.Minute)); ok {
+		t.Fatalf("Expected decorated mocks not to report a quota")
+	}
+}
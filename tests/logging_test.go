@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// logRecords decodes the records written by a slog.JSONHandler
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Unexpected log line %q: %s", line, err)
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestLoggerLogsRequests(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	server.FailNext("SendMessage", "NomiStillResponding")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	client := server.Client(nomi.WithRetry(fastRetryPolicy()), nomi.WithLogger(logger, nomi.WithLogLevels(slog.LevelInfo, slog.LevelWarn), nomi.WithBodies()))
+
+	if _, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "secret plans"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected a record per request, got %v", records)
+	}
+
+	failed, sent := records[0], records[1]
+	if failed["level"] != "WARN" || failed["error_type"] != "NomiStillResponding" || failed["status"] != 409.0 || failed["attempt"] != 1.0 {
+		t.Errorf("Unexpected record of the failed attempt %v", failed)
+	}
+	if sent["level"] != "INFO" || sent["operation"] != "SendMessage" || sent["method"] != "POST" || sent["status"] != 200.0 {
+		t.Errorf("Unexpected record of the request %v", sent)
+	}
+	if sent["path"] != "/v1/nomis/"+alice.UUID.String()+"/chat" || sent["duration"] == nil || sent["error_type"] != nil {
+		t.Errorf("Unexpected record of the request %v", sent)
+	}
+	if strings.Contains(buf.String(), "secret plans") {
+		t.Errorf("Expected the bodies to be logged only at debug level, got %s", buf.String())
+	}
+}
+
+func TestLoggerLogsBodiesAtDebug(t *testing.T) {
+	server := nomitest.NewServer()
+	server.APIKey = "very-secret-key"
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := server.Client(nomi.WithLogger(logger))
+	if _, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "secret plans"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if records := logRecords(t, &buf); len(records) != 1 || strings.Contains(buf.String(), "secret plans") {
+		t.Fatalf("Expected the bodies not to be logged without WithBodies, got %s", buf.String())
+	}
+
+	buf.Reset()
+	client = server.Client(nomi.WithLogger(logger, nomi.WithBodies()))
+	if _, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "secret plans"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected the request and its bodies to be logged, got %v", records)
+	}
+	bodies := records[1]
+	if bodies["level"] != "DEBUG" || !strings.Contains(bodies["request_body"].(string), "secret plans") ||
+		!strings.Contains(bodies["response_body"].(string), "Alice heard: secret plans") {
+		t.Errorf("Unexpected record of the bodies %v", bodies)
+	}
+	headers := bodies["headers"].(map[string]any)
+	if headers["Authorization"] != "REDACTED" || headers["Content-Type"] != "application/json" {
+		t.Errorf("Expected the Authorization header to be redacted, got %v", headers)
+	}
+	if strings.Contains(buf.String(), "very-secret-key") {
+		t.Errorf("Expected the API key not to be logged, got %s", buf.String())
+	}
+}
+
+func TestLoggerLogsErrorTypes(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client := server.Client(nomi.WithLogger(logger))
+
+	server.Fail("GetRooms", "LimitExceeded")
+	if _, err := client.GetRooms(); !errors.Is(err, nomi.LimitExceeded) {
+		t.Fatalf("Expected LimitExceeded, got %v", err)
+	}
+
+	records := logRecords(t, &buf)
+	if len(records) != 1 || records[0]["level"] != "ERROR" || records[0]["error_type"] != "LimitExceeded" {
+		t.Fatalf("Unexpected records %v", records)
+	}
+}
+
+func TestLoggerLogsRejectedCalls(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client := server.Client(nomi.WithLogger(logger))
+
+	if _, err := client.GetRoom("lounge"); !errors.Is(err, nomi.InvalidRouteParams) {
+		t.Fatalf("Expected InvalidRouteParams, got %v", err)
+	}
+
+	records := logRecords(t, &buf)
+	if len(records) != 1 || records[0]["level"] != "ERROR" || records[0]["error_type"] != "InvalidRouteParams" ||
+		records[0]["operation"] != "GetRoom" || records[0]["attempt"] != 0.0 || records[0]["path"] != "/v1/rooms/lounge" {
+		t.Fatalf("Unexpected records %v", records)
+	}
+}
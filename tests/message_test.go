@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/vhalmd/nomi-go-sdk"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMessageLengthIsValidatedLocally(t *testing.T) {
+	called := false
+	c := newTestServer(t, http.StatusOK, "{}", func(r *http.Request) {
+		called = true
+	}, nomi.WithPlan(nomi.PlanFree))
+
+	_, err := c.SendMessage(testID.String(), nomi.SendMessageBody{MessageText: strings.Repeat("a", 401)})
+	if !errors.Is(err, nomi.MessageLengthLimitExceeded) {
+		t.Fatalf("Expected MessageLengthLimitExceeded, got %v", err)
+	}
+	_, err = c.SendRoomMessage(testID.String(), nomi.SendRoomMessageBody{MessageText: strings.Repeat("é", 401)})
+	if !errors.Is(err, nomi.MessageLengthLimitExceeded) {
+		t.Fatalf("Expected MessageLengthLimitExceeded, got %v", err)
+	}
+	if called {
+		t.Fatalf("Expected messages that are too long not to be sent")
+	}
+
+	_, err = c.SendMessage(testID.String(), nomi.SendMessageBody{MessageText: strings.Repeat("é", 400)})
+	if err != nil {
+		t.Fatalf("Expected a message of 400 characters to be sent. Err: %s", err)
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	text := "This is the first sentence. And here is a second one! Is this the third? Yes, and it ends here."
+
+	parts := nomi.SplitMessage(text, 60)
+	expected := []string{
+		"This is the first sentence. And here is a second one!",
+		"Is this the third? Yes, and it ends here.",
+	}
+	if strings.Join(parts, "|") != strings.Join(expected, "|") {
+		t.Fatalf("Expected %q, got %q", expected, parts)
+	}
+
+	long := strings.Repeat("word ", 30) + strings.Repeat("x", 25)
+	for _, part := range nomi.SplitMessage(long, 20) {
+		if utf8.RuneCountInString(part) > 20 {
+			t.Fatalf("Expected every part to fit in 20 characters, got %q", part)
+		}
+	}
+
+	if parts := nomi.SplitMessage("short", 400); len(parts) != 1 || parts[0] != "short" {
+		t.Fatalf("Expected a short message to be kept as is, got %q", parts)
+	}
+}
+
+func TestSendSplitMessageSendsPartsInOrder(t *testing.T) {
+	var sent []string
+	c := newTestServer(t, http.StatusOK, "{}", func(r *http.Request) {
+		var body nomi.SendMessageBody
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sent = append(sent, body.MessageText)
+	})
+
+	responses, err := nomi.SendSplitMessage(context.Background(), c, testID.String(), "One. Two. Three.", 9)
+	if err != nil {
+		t.Fatalf("Unexpected error. Err: %s", err)
+	}
+
+	if len(responses) != 2 || strings.Join(sent, "|") != "One. Two.|Three." {
+		t.Fatalf("Expected the parts to be sent in order, got %q", sent)
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomimock"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+type recordingTB struct {
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMockRunsFunctionsAndRecordsCalls(t *testing.T) {
+	mock := &nomimock.API{
+		SendMessageContextFunc: func(ctx context.Context, nomiID string, body nomi.SendMessageBody) (nomi.SendMessageResponse, error) {
+			return nomi.SendMessageResponse{ReplyMessage: nomi.Message{Text: "echo: " + body.MessageText}}, nil
+		},
+	}
+	mock.Expect("SendMessage", 1)
+
+	res, err := mock.SendMessage("nomi-id", nomi.SendMessageBody{MessageText: "Hi"})
+	if err != nil || res.ReplyMessage.Text != "echo: Hi" {
+		t.Fatalf("Expected SendMessage to fall back to SendMessageContextFunc, got %+v (err: %v)", res, err)
+	}
+
+	_, err = mock.GetRooms()
+	if !errors.Is(err, nomimock.ErrNotMocked) {
+		t.Fatalf("Expected ErrNotMocked for a method without function, got %v", err)
+	}
+
+	calls := mock.CallsTo("SendMessage")
+	if len(calls) != 1 || calls[0].Args[0] != "nomi-id" || calls[0].Args[1].(nomi.SendMessageBody).MessageText != "Hi" {
+		t.Fatalf("Expected the arguments to be captured, got %+v", calls)
+	}
+	if len(mock.Calls()) != 2 {
+		t.Fatalf("Expected 2 calls to be recorded, got %d", len(mock.Calls()))
+	}
+
+	tb := &recordingTB{}
+	mock.AssertExpectations(tb)
+	if len(tb.errors) != 0 {
+		t.Fatalf("Expected the expectations to be met, got %v", tb.errors)
+	}
+
+	mock.Expect("DeleteRoom", 1)
+	mock.AssertExpectations(tb)
+	if len(tb.errors) != 1 {
+		t.Fatalf("Expected the missing DeleteRoom call to be reported, got %v", tb.errors)
+	}
+}
+
+func TestMockCountsContextVariants(t *testing.T) {
+	mock := &nomimock.API{}
+	mock.Expect("SendMessage", 2)
+	mock.Expect("SendMessageContext", 1)
+
+	_, _ = mock.SendMessage("nomi-id", nomi.SendMessageBody{MessageText: "Hi"})
+	_, _ = mock.SendMessageContext(context.Background(), "nomi-id", nomi.SendMessageBody{MessageText: "Hi"})
+
+	if calls := mock.CallsTo("SendMessage"); len(calls) != 2 || calls[0].Method != "SendMessage" || calls[1].Method != "SendMessageContext" {
+		t.Fatalf("Expected the calls to both variants, got %+v", calls)
+	}
+
+	tb := &recordingTB{}
+	mock.AssertExpectations(tb)
+	if len(tb.errors) != 0 {
+		t.Fatalf("Expected the expectations to be met, got %v", tb.errors)
+	}
+}
+
+func TestMockIsUpToDate(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go command is not available")
+	}
+
+	output := filepath.Join(t.TempDir(), "api.go")
+	cmd := exec.Command("go", "run", "../nomimock/internal/gen", "../base.go", output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Could not run the generator. Err: %s\n%s", err, out)
+	}
+
+	generated, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile("../nomimock/api.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(generated, current) {
+		t.Fatalf("nomimock/api.go is out of date with the API interface, run go generate ./nomimock")
+	}
+}
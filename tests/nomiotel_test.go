@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomiotel"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func newTelemetryClient(t *testing.T, server *nomitest.Server, opts ...nomi.Option) (nomi.API, *tracetest.InMemoryExporter, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	interceptor, err := nomiotel.NewInterceptor(nomiotel.WithTracerProvider(tracerProvider), nomiotel.WithMeterProvider(meterProvider))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	return server.Client(append(opts, nomi.WithInterceptors(interceptor))...), exporter, reader
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+// metricSums returns the value of the counter name by the value of the attribute key
+func metricSums(t *testing.T, reader *sdkmetric.ManualReader, name string, key attribute.Key) map[string]int64 {
+	t.Helper()
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	sums := map[string]int64{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name != name {
+				continue
+			}
+			for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				value, _ := point.Attributes.Value(key)
+				sums[value.AsString()] += point.Value
+			}
+		}
+	}
+
+	return sums
+}
+
+func TestTelemetrySpans(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	room := server.AddRoom("Club", nomi.StatusDefault, alice.UUID)
+	server.FailNext("SendMessage", "NomiStillResponding")
+
+	client, exporter, _ := newTelemetryClient(t, server, nomi.WithRetry(fastRetryPolicy()))
+	if _, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "Héllo"}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, err := client.RequestNomiRoomMessage(room.UUID.String(), nomi.RequestNomiRoomMessageBody{NomiUUID: alice.UUID})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	server.Fail("GetRoom", "RoomNotFound")
+	if _, err := client.GetRoom(room.UUID.String()); !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected RoomNotFound, got %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	sent := spans[0]
+	if sent.Name != "SendMessage" || spanAttribute(sent, nomiotel.NomiUUIDKey).AsString() != alice.UUID.String() {
+		t.Errorf("Unexpected span %s %v", sent.Name, sent.Attributes)
+	}
+	if spanAttribute(sent, nomiotel.MessageLengthKey).AsInt64() != 5 || spanAttribute(sent, nomiotel.RetriesKey).AsInt64() != 1 {
+		t.Errorf("Expected the message length and the retry to be recorded, got %v", sent.Attributes)
+	}
+	if spanAttribute(sent, nomiotel.StatusCodeKey).AsInt64() != 200 || sent.Status.Code == codes.Error {
+		t.Errorf("Expected a successful span, got %v, %v", sent.Attributes, sent.Status)
+	}
+
+	requested := spans[1]
+	if spanAttribute(requested, nomiotel.RoomUUIDKey).AsString() != room.UUID.String() ||
+		spanAttribute(requested, nomiotel.NomiUUIDKey).AsString() != alice.UUID.String() {
+		t.Errorf("Expected the Room and the Nomi to be recorded, got %v", requested.Attributes)
+	}
+
+	failed := spans[2]
+	if failed.Status.Code != codes.Error || spanAttribute(failed, nomiotel.ErrorTypeKey).AsString() != "RoomNotFound" ||
+		spanAttribute(failed, nomiotel.StatusCodeKey).AsInt64() != 404 || len(failed.Events) != 1 {
+		t.Errorf("Expected a failed span, got %v, %v", failed.Attributes, failed.Status)
+	}
+}
+
+func TestTelemetryMetrics(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	room := server.AddRoom("Club", nomi.StatusDefault, alice.UUID)
+
+	client, _, reader := newTelemetryClient(t, server, nomi.WithDailyQuota(2))
+	for range 2 {
+		if _, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "Hi"}); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if _, err := client.SendRoomMessage(room.UUID.String(), nomi.SendRoomMessageBody{MessageText: "Hi"}); !errors.Is(err, nomi.LimitExceeded) {
+		t.Fatalf("Expected the quota to be exhausted, got %v", err)
+	}
+	server.FailNext("GetNomis", "NoReply")
+	_, _ = client.GetNomis()
+	_, _ = client.GetNomis()
+
+	messages := metricSums(t, reader, "nomi.client.messages", nomiotel.OperationKey)
+	if messages["SendMessage"] != 2 || messages["SendRoomMessage"] != 0 {
+		t.Errorf("Unexpected messages sent %v", messages)
+	}
+	errs := metricSums(t, reader, "nomi.client.errors", nomiotel.ErrorTypeKey)
+	if len(errs) != 2 || errs["LimitExceeded"] != 1 || errs["NoReply"] != 1 {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	calls := uint64(0)
+	for _, m := range data.ScopeMetrics[0].Metrics {
+		if m.Name == "nomi.client.duration" {
+			for _, point := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+				calls += point.Count
+			}
+		}
+	}
+	if calls != 5 {
+		t.Errorf("Expected the duration of 5 calls, got %d", calls)
+	}
+}
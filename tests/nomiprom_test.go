@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomiprom"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusCollector(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+
+	collector := nomiprom.NewCollector()
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+
+	client := server.Client(nomi.WithInterceptors(collector.Interceptor()), nomi.WithDailyQuota(3))
+	collector.TrackQuota(client)
+
+	for range 2 {
+		if _, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "Hi"}); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	server.FailNext("SendMessage", "NoReply")
+	if _, err := client.SendMessage(alice.UUID.String(), nomi.SendMessageBody{MessageText: "Hi"}); !errors.Is(err, nomi.NoReply) {
+		t.Fatalf("Expected NoReply, got %v", err)
+	}
+	if _, err := client.GetRoom(alice.UUID.String()); !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected RoomNotFound, got %v", err)
+	}
+
+	expected := `
+# HELP nomi_client_calls_total Number of calls to the Nomi API.
+# TYPE nomi_client_calls_total counter
+nomi_client_calls_total{operation="GetRoom"} 1
+nomi_client_calls_total{operation="SendMessage"} 3
+# HELP nomi_client_errors_total Number of failed calls to the Nomi API, by error type.
+# TYPE nomi_client_errors_total counter
+nomi_client_errors_total{error_type="NoReply",operation="SendMessage"} 1
+nomi_client_errors_total{error_type="RoomNotFound",operation="GetRoom"} 1
+# HELP nomi_client_in_flight_requests Number of calls to the Nomi API in progress.
+# TYPE nomi_client_in_flight_requests gauge
+nomi_client_in_flight_requests{operation="GetRoom"} 0
+nomi_client_in_flight_requests{operation="SendMessage"} 0
+# HELP nomi_client_quota_remaining_messages Number of messages left in the daily quota of the client.
+# TYPE nomi_client_quota_remaining_messages gauge
+nomi_client_quota_remaining_messages 1
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"nomi_client_calls_total", "nomi_client_errors_total", "nomi_client_in_flight_requests", "nomi_client_quota_remaining_messages")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := testutil.CollectAndCount(collector, "nomi_client_request_duration_seconds"); count != 2 {
+		t.Fatalf("Expected a histogram per operation, got %d", count)
+	}
+}
+
+func TestPrometheusInFlight(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+
+	collector := nomiprom.NewCollector(nomiprom.WithNamespace("app"))
+	inFlight := -1.0
+	client := server.Client(nomi.WithInterceptors(collector.Interceptor(), nomi.Interceptor{
+		BeforeSend: func(ctx context.Context, call *nomi.Call) (context.Context, error) {
+			// the in-flight gauge is the only metric collected before the first call ends
+			inFlight = testutil.ToFloat64(collector)
+			return nil, nil
+		},
+	}))
+
+	if _, err := client.GetNomis(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if inFlight != 1 {
+		t.Fatalf("Expected 1 call in flight, got %v", inFlight)
+	}
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"testing"
+)
+
+func TestFakeServerRoomLifecycle(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	c := server.Client()
+
+	room, err := c.CreateRoom(nomi.CreateRoomBody{Name: "test-sdk", NomiUUIDs: []uuid.UUID{alice.UUID}})
+	if err != nil {
+		t.Fatalf("Could not create a room. Err: %s", err)
+	}
+
+	rooms, err := c.GetRooms()
+	if err != nil || len(rooms.Rooms) != 1 || rooms.Rooms[0].Name != "test-sdk" {
+		t.Fatalf("Expected the room to be listed, got %+v (err: %v)", rooms, err)
+	}
+
+	name := "renamed"
+	updated, err := c.UpdateRoom(room.UUID.String(), nomi.UpdateRoomBody{Name: &name})
+	if err != nil || updated.Name != "renamed" || len(updated.Nomis) != 1 {
+		t.Fatalf("Expected the room to be renamed, got %+v (err: %v)", updated, err)
+	}
+
+	_, err = c.SendRoomMessage(room.UUID.String(), nomi.SendRoomMessageBody{MessageText: "Hello"})
+	if err != nil {
+		t.Fatalf("Could not send a message in the room. Err: %s", err)
+	}
+
+	server.Script(alice.UUID, "Hey there")
+	reply, err := c.RequestNomiRoomMessage(room.UUID.String(), nomi.RequestNomiRoomMessageBody{NomiUUID: alice.UUID})
+	if err != nil || reply.ReplyMessage.Text != "Hey there" {
+		t.Fatalf("Expected the scripted reply, got %+v (err: %v)", reply, err)
+	}
+
+	_, err = c.RequestNomiRoomMessage(room.UUID.String(), nomi.RequestNomiRoomMessageBody{NomiUUID: uuid.New()})
+	if !errors.Is(err, nomi.RoomNomiNotFound) {
+		t.Fatalf("Expected RoomNomiNotFound for a Nomi outside the room, got %v", err)
+	}
+
+	if messages := server.Messages(room.UUID); len(messages) != 2 {
+		t.Fatalf("Expected 2 messages in the room, got %d", len(messages))
+	}
+
+	success, err := c.DeleteRoom(room.UUID.String())
+	if err != nil || !success {
+		t.Fatalf("Could not delete the room. Err: %v", err)
+	}
+
+	_, err = c.GetRoom(room.UUID.String())
+	if !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected RoomNotFound after deleting the room, got %v", err)
+	}
+}
+
+func TestFakeServerChat(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+
+	bob := server.AddNomi("Bob", nomi.MALE, nomi.MENTOR)
+	server.SetReplyFunc(func(n nomi.Nomi, text string) string {
+		return n.Name + " says hi back"
+	})
+	c := server.Client()
+
+	res, err := c.SendMessage(bob.UUID.String(), nomi.SendMessageBody{MessageText: "Hi"})
+	if err != nil {
+		t.Fatalf("Could not send a message. Err: %s", err)
+	}
+	if res.SentMessage.Text != "Hi" || res.ReplyMessage.Text != "Bob says hi back" {
+		t.Fatalf("Unexpected response %+v", res)
+	}
+
+	_, err = c.SendMessage(bob.UUID.String(), nomi.SendMessageBody{})
+	var apiErr *nomi.Error
+	if !errors.Is(err, nomi.InvalidBody) || !errors.As(err, &apiErr) || len(apiErr.Issues) == 0 {
+		t.Fatalf("Expected InvalidBody with issues for an empty message, got %v", err)
+	}
+
+	_, err = c.GetNomi(uuid.NewString())
+	if !errors.Is(err, nomi.NotFound) {
+		t.Fatalf("Expected NotFound for an unknown Nomi, got %v", err)
+	}
+}
+
+func TestFakeServerInjectsEveryErrorType(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	c := server.Client()
+
+	for _, errorType := range nomi.ErrorTypes() {
+		server.FailNext("GetNomis", errorType)
+
+		_, err := c.GetNomis()
+
+		var apiErr *nomi.Error
+		if !errors.As(err, &apiErr) || apiErr.Type != errorType {
+			t.Errorf("Expected a %s error, got %v", errorType, err)
+			continue
+		}
+		if apiErr.Unwrap() == nil {
+			t.Errorf("Expected %s to match a sentinel error", errorType)
+		}
+	}
+
+	_, err := c.GetNomis()
+	if err != nil {
+		t.Fatalf("Expected the injected failures to be used up. Err: %s", err)
+	}
+	if calls := server.Calls("GetNomis"); calls != len(nomi.ErrorTypes())+1 {
+		t.Fatalf("Expected %d calls, got %d", len(nomi.ErrorTypes())+1, calls)
+	}
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/internal/cli"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"github.com/vhalmd/nomi-go-sdk/provision"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProvisionConverges(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	bob := server.AddNomi("Bob", nomi.MALE, nomi.FRIEND)
+	carol := server.AddNomi("Carol", nomi.FEMALE, nomi.MENTOR)
+	server.AddRoom("Lounge", nomi.StatusDefault, alice.UUID)
+	server.AddRoom("Old", nomi.StatusDefault, bob.UUID)
+	server.AddRoom("Same", nomi.StatusDefault, carol.UUID)
+	client := server.Client()
+	ctx := context.Background()
+
+	spec, err := provision.Parse([]byte(fmt.Sprintf(`
+prune: true
+rooms:
+  - name: Lounge
+    note: Chill
+    nomis: [alice, %s]
+  - name: Same
+    nomis: [Carol]
+  - name: Book club
+    backchanneling: true
+    nomis: [Alice, Bob, Carol]
+`, bob.UUID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := provision.NewPlan(ctx, client, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `~ update room "Lounge"
+    note: "" -> "Chill"
+    nomis: Alice -> Alice, Bob
++ create room "Book club"
+    nomis: Alice, Bob, Carol
+- delete room "Old"
+Plan: 1 to create, 1 to update, 1 to delete.
+`
+	if plan.String() != want {
+		t.Fatalf("Expected the plan\n%s\ngot\n%s", want, plan)
+	}
+	if server.Calls("CreateRoom")+server.Calls("UpdateRoom")+server.Calls("DeleteRoom") != 0 {
+		t.Fatal("Expected planning to change nothing")
+	}
+
+	err = plan.Apply(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, room := range server.Rooms() {
+		names = append(names, room.Name)
+		if room.Name == "Lounge" && (room.Note != "Chill" || len(room.Nomis) != 2) {
+			t.Fatalf("Expected the Lounge to be updated, got %+v", room)
+		}
+		if room.Name == "Book club" && (!room.BackchannelingEnabled || len(room.Nomis) != 3) {
+			t.Fatalf("Expected the Book club to be created, got %+v", room)
+		}
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"Book club", "Lounge", "Same"}) {
+		t.Fatalf("Expected the rooms of the spec, got %v", names)
+	}
+
+	plan, err = provision.NewPlan(ctx, client, spec)
+	if err != nil || len(plan.Changes) != 0 || !strings.HasPrefix(plan.String(), "No changes") {
+		t.Fatalf("Expected nothing left to do, got %s, %v", plan, err)
+	}
+}
+
+func TestProvisionRespectsLimits(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	server.AddNomi("Twin", nomi.FEMALE, nomi.FRIEND)
+	server.AddNomi("Twin", nomi.MALE, nomi.FRIEND)
+	client := server.Client()
+	ctx := context.Background()
+
+	room := func(name string, nomis ...string) provision.RoomSpec {
+		return provision.RoomSpec{Name: name, Nomis: nomis}
+	}
+	eleven := make([]string, 11)
+	var tooMany provision.Spec
+	for i := range 11 {
+		eleven[i] = "Alice"
+		tooMany.Rooms = append(tooMany.Rooms, room(fmt.Sprint("Room ", i), "Alice"))
+	}
+
+	for name, test := range map[string]struct {
+		spec provision.Spec
+		want error
+	}{
+		"no nomi":        {provision.Spec{Rooms: []provision.RoomSpec{room("Empty")}}, nomi.RoomNomiCountTooSmall},
+		"too many nomis": {provision.Spec{Rooms: []provision.RoomSpec{room("Crowd", eleven...)}}, nomi.RoomNomiCountTooLarge},
+		"too many rooms": {tooMany, nomi.ExceededRoomLimit},
+		"unknown nomi":   {provision.Spec{Rooms: []provision.RoomSpec{room("Lounge", "Zed")}}, nomi.NotFound},
+	} {
+		if _, err := provision.NewPlan(ctx, client, test.spec); !errors.Is(err, test.want) {
+			t.Errorf("%s: expected %v, got %v", name, test.want, err)
+		}
+	}
+
+	_, err := provision.NewPlan(ctx, client, provision.Spec{Rooms: []provision.RoomSpec{room("Lounge", "Twin")}})
+	var ambiguous *nomi.AmbiguousNameError
+	if !errors.As(err, &ambiguous) || len(ambiguous.UUIDs) != 2 {
+		t.Fatalf("Expected an ambiguous name to be refused, got %v", err)
+	}
+
+	for i := range 9 {
+		server.AddRoom(fmt.Sprint("Existing ", i), nomi.StatusDefault, alice.UUID)
+	}
+	spec := provision.Spec{Rooms: []provision.RoomSpec{room("New 1", "Alice"), room("New 2", "Alice")}}
+	if _, err := provision.NewPlan(ctx, client, spec); !errors.Is(err, nomi.ExceededRoomLimit) {
+		t.Fatalf("Expected the rooms kept outside the spec to count, got %v", err)
+	}
+	spec.Prune = true
+	if _, err := provision.NewPlan(ctx, client, spec); err != nil {
+		t.Fatalf("Expected pruning to make room, got %v", err)
+	}
+
+	if _, err := provision.Parse([]byte(`{"rooms": [], "unknown": 1}`)); err == nil {
+		t.Fatal("Expected unknown fields to be refused")
+	}
+	for _, empty := range []string{"", "# no rooms yet\n"} {
+		if _, err := provision.Parse([]byte(empty)); !errors.Is(err, provision.ErrEmptySpec) {
+			t.Fatalf("Expected ErrEmptySpec for %q, got %v", empty, err)
+		}
+	}
+	if spec, err := provision.Parse([]byte("rooms: []")); err != nil || len(spec.Rooms) != 0 {
+		t.Fatalf("Expected a spec without rooms, got %+v, %v", spec, err)
+	}
+}
+
+func TestCLIAppliesRoomSpec(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+
+	path := filepath.Join(t.TempDir(), "rooms.json")
+	err := os.WriteFile(path, []byte(`{"rooms": [{"name": "Lounge", "nomis": ["Alice"]}]}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, server, "rooms", "apply", path, "--dry-run")
+	if code != cli.ExitOK || !strings.Contains(stdout, `+ create room "Lounge"`) || len(server.Rooms()) != 0 {
+		t.Fatalf("Expected the plan only, got code %d, %q %q", code, stdout, stderr)
+	}
+
+	code, stdout, stderr = runCLI(t, server, "rooms", "apply", path)
+	if code != cli.ExitOK || !strings.HasSuffix(stdout, "Applied.\n") || len(server.Rooms()) != 1 {
+		t.Fatalf("Expected the Room to be created, got code %d, %q %q", code, stdout, stderr)
+	}
+}
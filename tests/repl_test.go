@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/internal/cli"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"strings"
+	"testing"
+)
+
+func TestREPLChatsWithNomi(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	server.FailNext("SendMessage", "NomiStillResponding")
+
+	input := "Hello\nHello again\n/who\n/note hi\n/quit\nnever sent\n"
+	code, stdout, stderr := runCLIWithInput(t, server, input, "chat", "repl", alice.UUID.String())
+	if code != cli.ExitOK {
+		t.Fatalf("Expected the session to end normally, got code %d, %q", code, stderr)
+	}
+
+	for _, want := range []string{
+		"Chatting with Alice",
+		"Alice is still replying",
+		"] you: Hello again",
+		"] Alice: Alice heard: Hello again",
+		"Alice (Female, Friend)",
+		"/note only works in Rooms",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected the output to contain %q, got %q", want, stdout)
+		}
+	}
+	if len(server.Messages(alice.UUID)) != 2 {
+		t.Fatalf("Expected one message and its reply, got %v", server.Messages(alice.UUID))
+	}
+}
+
+func TestREPLChatsInRoom(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	bob := server.AddNomi("Bob", nomi.MALE, nomi.MENTOR)
+	room := server.AddRoom("Book club", nomi.StatusDefault, alice.UUID, bob.UUID)
+	server.Script(bob.UUID, "I loved it")
+	server.FailNext("RequestNomiRoomMessage", "NoReply")
+
+	input := "What did you think of the book?\n/ask bob\n/ask bob\n/ask Carol\n/rename Reading club\n/note Talk about books\n/note\n/unknown\n"
+	code, stdout, stderr := runCLIWithInput(t, server, input, "rooms", "repl", room.UUID.String())
+	if code != cli.ExitOK {
+		t.Fatalf("Expected the session to end at the end of the input, got code %d, %q", code, stderr)
+	}
+
+	for _, want := range []string{
+		"Room Book club with Alice, Bob",
+		"] you: What did you think of the book?",
+		"Bob did not reply",
+		"] Bob: I loved it",
+		"there is no Carol in this Room",
+		"Reading club updated",
+		"note: Talk about books",
+		"unknown command /unknown",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected the output to contain %q, got %q", want, stdout)
+		}
+	}
+	if rooms := server.Rooms(); rooms[0].Name != "Reading club" || rooms[0].Note != "Talk about books" {
+		t.Fatalf("Expected the Room to be renamed and its note changed, got %+v", rooms[0])
+	}
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"testing"
+	"time"
+)
+
+func TestResolverFindsByNameOrUUID(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	twin1 := server.AddNomi("Twin", nomi.FEMALE, nomi.FRIEND)
+	twin2 := server.AddNomi("Twin", nomi.MALE, nomi.FRIEND)
+	room := server.AddRoom("Book club", nomi.StatusDefault, alice.UUID)
+	resolver := nomi.NewResolver(server.Client(), time.Minute)
+	ctx := context.Background()
+
+	n, err := resolver.Nomi(ctx, "alice")
+	if err != nil || n.UUID != alice.UUID {
+		t.Fatalf("Expected Alice, got %+v, %v", n, err)
+	}
+	id, err := resolver.NomiID(ctx, alice.UUID.String())
+	if err != nil || id != alice.UUID.String() {
+		t.Fatalf("Expected the UUID as it is, got %s, %v", id, err)
+	}
+	id, err = resolver.RoomID(ctx, "BOOK CLUB")
+	if err != nil || id != room.UUID.String() {
+		t.Fatalf("Expected the Book club, got %s, %v", id, err)
+	}
+
+	_, err = resolver.Nomi(ctx, "Twin")
+	var ambiguous *nomi.AmbiguousNameError
+	if !errors.As(err, &ambiguous) || ambiguous.Kind != "Nomi" || len(ambiguous.UUIDs) != 2 || ambiguous.UUIDs[0] != twin1.UUID || ambiguous.UUIDs[1] != twin2.UUID {
+		t.Fatalf("Expected an ambiguity between the twins, got %v", err)
+	}
+
+	if _, err := resolver.Nomi(ctx, "Nobody"); !errors.Is(err, nomi.NotFound) {
+		t.Fatalf("Expected NotFound, got %v", err)
+	}
+	if _, err := resolver.Room(ctx, "Nowhere"); !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected RoomNotFound, got %v", err)
+	}
+}
+
+func TestResolverCachesLists(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	resolver := nomi.NewResolver(server.Client(), time.Minute)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := resolver.Nomi(ctx, "Alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if server.Calls("GetNomis") != 1 {
+		t.Fatalf("Expected the list to be kept, got %d calls", server.Calls("GetNomis"))
+	}
+
+	server.AddNomi("Bob", nomi.MALE, nomi.FRIEND)
+	if _, err := resolver.Nomi(ctx, "Bob"); err != nil || server.Calls("GetNomis") != 2 {
+		t.Fatalf("Expected a missing name to be looked up again, got %v after %d calls", err, server.Calls("GetNomis"))
+	}
+
+	resolver.Invalidate()
+	if _, err := resolver.Nomi(ctx, "Alice"); err != nil || server.Calls("GetNomis") != 3 {
+		t.Fatalf("Expected Invalidate to drop the list, got %v after %d calls", err, server.Calls("GetNomis"))
+	}
+
+	expiring := nomi.NewResolver(server.Client(), time.Millisecond)
+	expiring.Nomi(ctx, "Alice")
+	time.Sleep(5 * time.Millisecond)
+	expiring.Nomi(ctx, "Alice")
+	if server.Calls("GetNomis") != 5 {
+		t.Fatalf("Expected the list to expire, got %d calls", server.Calls("GetNomis"))
+	}
+}
+
+func TestNameResolutionClient(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	client := nomi.WithNameResolution(server.Client(), time.Minute)
+
+	res, err := client.SendMessage("Alice", nomi.SendMessageBody{MessageText: "Hi"})
+	if err != nil || res.ReplyMessage.Text != "Alice heard: Hi" {
+		t.Fatalf("Expected the message to reach Alice, got %+v, %v", res, err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.GetRoomContext(ctx, "Lounge"); !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected RoomNotFound before the Room is created, got %v", err)
+	}
+	if _, err := client.CreateRoom(nomi.CreateRoomBody{Name: "Lounge", NomiUUIDs: []uuid.UUID{alice.UUID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "Reading room"
+	if _, err := client.UpdateRoom("lounge", nomi.UpdateRoomBody{Name: &name}); err != nil {
+		t.Fatalf("Expected to update the Lounge by name, got %v", err)
+	}
+	room, err := client.GetRoomContext(ctx, "Reading room")
+	if err != nil || room.Name != "Reading room" {
+		t.Fatalf("Expected the renamed Room, got %+v, %v", room, err)
+	}
+	if _, err := client.GetRoom("Lounge"); !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected the old name to be forgotten, got %v", err)
+	}
+
+	if deleted, err := client.DeleteRoom("Reading room"); !deleted || err != nil {
+		t.Fatalf("Expected the Room to be deleted, got %v", err)
+	}
+	if _, err := client.SendRoomMessage("Reading room", nomi.SendRoomMessageBody{MessageText: "Hi"}); !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected the deleted Room to be forgotten, got %v", err)
+	}
+}
+
+func TestFindInLists(t *testing.T) {
+	alice := nomi.Nomi{UUID: uuid.New(), Name: "Alice"}
+	twins := []nomi.Nomi{{UUID: uuid.New(), Name: "Twin"}, {UUID: uuid.New(), Name: "twin"}}
+	nomis := append([]nomi.Nomi{alice}, twins...)
+
+	if n, err := nomi.FindNomi(nomis, "ALICE"); err != nil || n.UUID != alice.UUID {
+		t.Fatalf("Expected Alice by name, got %+v, %v", n, err)
+	}
+	if n, err := nomi.FindNomi(nomis, alice.UUID.String()); err != nil || n.Name != "Alice" {
+		t.Fatalf("Expected Alice by UUID, got %+v, %v", n, err)
+	}
+	if _, err := nomi.FindNomi(nomis, uuid.NewString()); !errors.Is(err, nomi.NotFound) {
+		t.Fatalf("Expected NotFound for an unknown UUID, got %v", err)
+	}
+	var ambiguous *nomi.AmbiguousNameError
+	if _, err := nomi.FindNomi(nomis, "twin"); !errors.As(err, &ambiguous) || len(ambiguous.UUIDs) != 2 {
+		t.Fatalf("Expected an ambiguous name, got %v", err)
+	}
+
+	rooms := []nomi.Room{{UUID: uuid.New(), Name: "Lounge"}}
+	if _, err := nomi.FindRoom(rooms, "Kitchen"); !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected RoomNotFound, got %v", err)
+	}
+}
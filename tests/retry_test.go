@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer answers the first failures requests with status and body, then succeeds
+func newFlakyServer(t *testing.T, failures int32, status int, body string, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		_, _ = w.Write([]byte(`{"nomis":[]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func fastRetryPolicy() nomi.RetryPolicy {
+	policy := nomi.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	server, calls := newFlakyServer(t, 2, http.StatusConflict, `{"error":{"type":"NomiStillResponding"}}`, nil)
+
+	var attempts []nomi.Attempt
+	policy := fastRetryPolicy()
+	policy.OnAttempt = func(attempt nomi.Attempt) {
+		attempts = append(attempts, attempt)
+	}
+
+	c := nomi.NewClient("test-key", nomi.WithBaseURL(server.URL), nomi.WithRetry(policy))
+	_, err := c.GetNomis()
+	if err != nil {
+		t.Fatalf("Expected the call to succeed after retrying. Err: %s", err)
+	}
+
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 requests, got %d", calls.Load())
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("Expected 2 failed attempts to be reported, got %d", len(attempts))
+	}
+	if attempts[0].Operation != "GetNomis" || attempts[0].Number != 1 || !attempts[0].Retrying {
+		t.Errorf("Unexpected first attempt %+v", attempts[0])
+	}
+	if !errors.Is(attempts[1].Err, nomi.StillResponding) {
+		t.Errorf("Expected the attempt error to be StillResponding, got %v", attempts[1].Err)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	server, calls := newFlakyServer(t, 100, http.StatusServiceUnavailable, "", nil)
+
+	c := nomi.NewClient("test-key", nomi.WithBaseURL(server.URL), nomi.WithRetry(fastRetryPolicy()))
+	_, err := c.GetNomis()
+
+	var apiErr *nomi.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected the last 503 error, got %v", err)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("Expected 4 requests, got %d", calls.Load())
+	}
+}
+
+func TestRetrySkipsPermanentErrors(t *testing.T) {
+	server, calls := newFlakyServer(t, 1, http.StatusNotFound, `{"error":{"type":"NomiNotFound"}}`, nil)
+
+	c := nomi.NewClient("test-key", nomi.WithBaseURL(server.URL), nomi.WithRetry(fastRetryPolicy()))
+	_, err := c.GetNomis()
+
+	if !errors.Is(err, nomi.NotFound) {
+		t.Fatalf("Expected NotFound, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected a single request, got %d", calls.Load())
+	}
+}
+
+func TestRetryCanBeDisabledPerCall(t *testing.T) {
+	server, calls := newFlakyServer(t, 1, http.StatusTooManyRequests, "", nil)
+
+	c := nomi.NewClient("test-key", nomi.WithBaseURL(server.URL), nomi.WithRetry(fastRetryPolicy()))
+	_, err := c.GetNomisContext(nomi.WithoutRetry(context.Background()))
+
+	if err == nil {
+		t.Fatalf("Expected the 429 error to be returned without retrying")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected a single request, got %d", calls.Load())
+	}
+}
+
+func TestRetryDoesNotRepeatPosts(t *testing.T) {
+	roomID := uuid.NewString()
+	body := nomi.SendRoomMessageBody{MessageText: "Hello"}
+
+	server, calls := newFlakyServer(t, 1, http.StatusBadGateway, "", nil)
+	c := nomi.NewClient("test-key", nomi.WithBaseURL(server.URL), nomi.WithRetry(fastRetryPolicy()))
+	if _, err := c.SendRoomMessage(roomID, body); err == nil || calls.Load() != 1 {
+		t.Fatalf("Expected the 502 error without retrying, got %v after %d requests", err, calls.Load())
+	}
+	if _, err := c.GetNomis(); err != nil {
+		t.Fatalf("Expected GET calls to be retried. Err: %s", err)
+	}
+
+	server, calls = newFlakyServer(t, 1, http.StatusConflict, `{"error":{"type":"RoomNomiNotReadyForMessage"}}`, nil)
+	c = nomi.NewClient("test-key", nomi.WithBaseURL(server.URL), nomi.WithRetry(fastRetryPolicy()))
+	if _, err := c.SendRoomMessage(roomID, body); err != nil || calls.Load() != 2 {
+		t.Fatalf("Expected the temporary error to be retried, got %v after %d requests", err, calls.Load())
+	}
+
+	server, _ = newFlakyServer(t, 0, http.StatusOK, "", nil)
+	server.Close()
+	attempts := 0
+	policy := fastRetryPolicy()
+	policy.OnAttempt = func(nomi.Attempt) { attempts++ }
+	c = nomi.NewClient("test-key", nomi.WithBaseURL(server.URL), nomi.WithRetry(policy))
+	if _, err := c.SendRoomMessage(roomID, body); err == nil || attempts != 4 {
+		t.Fatalf("Expected failures to connect to be retried, got %v after %d attempts", err, attempts)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	server, _ := newFlakyServer(t, 1, http.StatusTooManyRequests, "", http.Header{"Retry-After": {"0"}})
+
+	var delays []time.Duration
+	policy := fastRetryPolicy()
+	policy.InitialBackoff = time.Hour
+	policy.MaxBackoff = time.Hour
+	policy.OnAttempt = func(attempt nomi.Attempt) {
+		delays = append(delays, attempt.Delay)
+	}
+
+	c := nomi.NewClient("test-key", nomi.WithBaseURL(server.URL), nomi.WithRetry(policy))
+	_, err := c.GetNomis()
+	if err != nil {
+		t.Fatalf("Expected the call to succeed after retrying. Err: %s", err)
+	}
+
+	if len(delays) != 1 || delays[0] != 0 {
+		t.Fatalf("Expected a single retry after the Retry-After delay, got %v", delays)
+	}
+}
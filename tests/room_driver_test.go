@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"testing"
+	"time"
+)
+
+// driveRoom runs DriveRoom until it stops and returns the replies and the last event
+func driveRoom(ctx context.Context, client nomi.API, room nomi.Room, opts nomi.DriveOptions) ([]nomi.RoomEvent, nomi.RoomEvent) {
+	var turns []nomi.RoomEvent
+	var last nomi.RoomEvent
+	for event := range nomi.DriveRoom(ctx, client, room, opts) {
+		if event.Stopped != "" {
+			last = event
+			continue
+		}
+		turns = append(turns, event)
+	}
+
+	return turns, last
+}
+
+func newDriverRoom(t *testing.T) (*nomitest.Server, nomi.Room) {
+	server := nomitest.NewServer()
+	t.Cleanup(server.Close)
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	bob := server.AddNomi("Bob", nomi.MALE, nomi.FRIEND)
+
+	return server, server.AddRoom("Lounge", nomi.StatusDefault, alice.UUID, bob.UUID)
+}
+
+func TestDriveRoomTakesTurns(t *testing.T) {
+	server, room := newDriverRoom(t)
+	server.FailNext("RequestNomiRoomMessage", "NoReply")
+
+	turns, last := driveRoom(context.Background(), server.Client(), room, nomi.DriveOptions{MaxTurns: 4, ErrorBackoff: fastRetryPolicy()})
+	if last.Stopped != nomi.StopMaxTurns || last.Err != nil {
+		t.Fatalf("Expected to stop after the last turn, got %+v", last)
+	}
+	if len(turns) != 4 || !errors.Is(turns[0].Err, nomi.NoReply) {
+		t.Fatalf("Expected 4 turns starting with NoReply, got %+v", turns)
+	}
+	for i, want := range []string{"Alice", "Bob", "Alice", "Bob"} {
+		if turns[i].Turn != i+1 || turns[i].Nomi.Name != want {
+			t.Fatalf("Expected turn %d to be %s, got %+v", i+1, want, turns[i])
+		}
+	}
+}
+
+func TestDriveRoomStopsOnKeyword(t *testing.T) {
+	server, room := newDriverRoom(t)
+	server.Script(room.Nomis[1].UUID, "Well, GOODBYE then")
+
+	turns, last := driveRoom(context.Background(), server.Client(), room, nomi.DriveOptions{MaxTurns: 10, StopWords: []string{"goodbye"}})
+	if last.Stopped != nomi.StopKeyword || len(turns) != 2 || turns[1].Message.Text != "Well, GOODBYE then" {
+		t.Fatalf("Expected to stop after the goodbye of Bob, got %+v then %+v", turns, last)
+	}
+}
+
+func TestDriveRoomBacksOffOnErrors(t *testing.T) {
+	server, room := newDriverRoom(t)
+	server.Fail("RequestNomiRoomMessage", "RoomNomiNotReadyForMessage")
+
+	// without jitter, the pauses after the failed turns are 10ms, 20ms and 40ms
+	policy := nomi.RetryPolicy{InitialBackoff: 10 * time.Millisecond, Multiplier: 2}
+	start := time.Now()
+	turns, last := driveRoom(context.Background(), server.Client(), room, nomi.DriveOptions{MaxTurns: 4, ErrorBackoff: policy})
+	if last.Stopped != nomi.StopMaxTurns || len(turns) != 4 || server.Calls("RequestNomiRoomMessage") != 4 {
+		t.Fatalf("Expected 4 failed turns, got %d then %+v", len(turns), last)
+	}
+	for _, turn := range turns {
+		if !errors.Is(turn.Err, nomi.RoomNomiNotReadyForMessage) {
+			t.Fatalf("Expected every turn to fail, got %+v", turn)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Fatalf("Expected the turns to be spaced by the growing backoff, took %s", elapsed)
+	}
+}
+
+func TestDriveRoomCountsRepliesAgainstQuota(t *testing.T) {
+	server, room := newDriverRoom(t)
+
+	client := server.Client(nomi.WithDailyQuota(5))
+	turns, last := driveRoom(context.Background(), client, room, nomi.DriveOptions{QuotaReserve: 2})
+	if last.Stopped != nomi.StopQuota || len(turns) != 3 {
+		t.Fatalf("Expected 3 replies before the reserve, got %d then %+v", len(turns), last)
+	}
+	if stats, _ := nomi.QuotaOf(client); stats.Used != 3 || stats.Remaining != 2 {
+		t.Fatalf("Expected the client to count the replies, got %+v", stats)
+	}
+}
+
+func TestDriveRoomStopsOnLimits(t *testing.T) {
+	server, room := newDriverRoom(t)
+
+	server.FailNext("RequestNomiRoomMessage", "LimitExceeded")
+	turns, last := driveRoom(context.Background(), server.Client(), room, nomi.DriveOptions{})
+	if last.Stopped != nomi.StopQuota || !errors.Is(last.Err, nomi.LimitExceeded) || len(turns) != 0 {
+		t.Fatalf("Expected LimitExceeded to stop the conversation, got %+v then %+v", turns, last)
+	}
+
+	server.FailNext("RequestNomiRoomMessage", "RoomNotFound")
+	_, last = driveRoom(context.Background(), server.Client(), room, nomi.DriveOptions{})
+	if last.Stopped != nomi.StopError || !errors.Is(last.Err, nomi.RoomNotFound) {
+		t.Fatalf("Expected RoomNotFound to stop the conversation, got %+v", last)
+	}
+
+	client := server.Client(nomi.WithDailyQuota(3))
+	if _, err := client.SendRoomMessage(room.UUID.String(), nomi.SendRoomMessageBody{MessageText: "Talk"}); err != nil {
+		t.Fatal(err)
+	}
+	_, last = driveRoom(context.Background(), client, room, nomi.DriveOptions{QuotaReserve: 2})
+	if last.Stopped != nomi.StopQuota || last.Err != nil {
+		t.Fatalf("Expected the quota reserve to stop the conversation, got %+v", last)
+	}
+
+	// turns start at least 20ms apart, so no more than 3 fit in 50ms however slow the requests are
+	calls := server.Calls("RequestNomiRoomMessage")
+	turns, last = driveRoom(context.Background(), server.Client(), room, nomi.DriveOptions{Interval: 20 * time.Millisecond, MaxDuration: 50 * time.Millisecond})
+	if last.Stopped != nomi.StopDuration || len(turns) > 3 || server.Calls("RequestNomiRoomMessage")-calls != len(turns) {
+		t.Fatalf("Expected a few turns before the end of the duration, got %d then %+v", len(turns), last)
+	}
+}
+
+func TestDriveRoomStopsWithContext(t *testing.T) {
+	server, room := newDriverRoom(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var turns int
+	var last nomi.RoomEvent
+	for event := range nomi.DriveRoom(ctx, server.Client(), room, nomi.DriveOptions{Interval: time.Millisecond}) {
+		if event.Stopped != "" {
+			last = event
+			continue
+		}
+		turns++
+		if turns == 3 {
+			cancel()
+		}
+	}
+	if last.Stopped != nomi.StopCanceled || !errors.Is(last.Err, context.Canceled) || turns != 3 {
+		t.Fatalf("Expected to stop after 3 turns, got %d then %+v", turns, last)
+	}
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomimock"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func sessionRoom(names ...string) nomi.Room {
+	room := nomi.Room{UUID: uuid.New(), Name: "Room"}
+	for _, name := range names {
+		room.Nomis = append(room.Nomis, nomi.Nomi{UUID: uuid.New(), Name: name})
+	}
+
+	return room
+}
+
+func names(nomis []nomi.Nomi) []string {
+	var names []string
+	for _, n := range nomis {
+		names = append(names, n.Name)
+	}
+
+	return names
+}
+
+func TestTurnStrategies(t *testing.T) {
+	room := sessionRoom("Alice", "Bob", "Carol")
+
+	if got := names(nomi.AllTurns().Next(room, "hi")); !slices.Equal(got, []string{"Alice", "Bob", "Carol"}) {
+		t.Errorf("Expected every Nomi to reply, got %v", got)
+	}
+
+	roundRobin := nomi.RoundRobinTurns()
+	var got []string
+	for range 4 {
+		got = append(got, names(roundRobin.Next(room, "hi"))...)
+	}
+	if !slices.Equal(got, []string{"Alice", "Bob", "Carol", "Alice"}) {
+		t.Errorf("Expected the Nomis to reply in turn, got %v", got)
+	}
+
+	for range 20 {
+		picked := nomi.RandomTurns().Next(room, "hi")
+		if len(picked) != 1 || !slices.Contains(room.Nomis, picked[0]) {
+			t.Fatalf("Expected a Nomi of the Room, got %v", picked)
+		}
+	}
+
+	weighted := nomi.WeightedTurns(map[uuid.UUID]float64{room.Nomis[0].UUID: 0, room.Nomis[1].UUID: 0, room.Nomis[2].UUID: 5})
+	for range 20 {
+		if got := names(weighted.Next(room, "hi")); !slices.Equal(got, []string{"Carol"}) {
+			t.Fatalf("Expected only Carol to have a weight, got %v", got)
+		}
+	}
+	if got := nomi.WeightedTurns(map[uuid.UUID]float64{room.Nomis[0].UUID: 0, room.Nomis[1].UUID: 0, room.Nomis[2].UUID: 0}).Next(room, "hi"); got != nil {
+		t.Fatalf("Expected nobody to reply without weight, got %v", got)
+	}
+}
+
+func TestMentionedTurns(t *testing.T) {
+	room := sessionRoom("Ann", "Anna", "Bob")
+	mentioned := nomi.MentionedTurns(nil)
+
+	for message, want := range map[string][]string{
+		"What do you think, bob and ANNA?": {"Bob", "Anna"},
+		"Ann, Anna: hello":                 {"Ann", "Anna"},
+		"Bobby and Annabel":                nil,
+		"Hello everyone":                   nil,
+	} {
+		if got := names(mentioned.Next(room, message)); !slices.Equal(got, want) {
+			t.Errorf("Expected %v to reply to %q, got %v", want, message, got)
+		}
+	}
+
+	fallback := nomi.MentionedTurns(nomi.AllTurns())
+	if got := fallback.Next(room, "Hello everyone"); len(got) != 3 {
+		t.Fatalf("Expected the fallback to pick the Nomis, got %v", got)
+	}
+}
+
+func TestRoomSessionStreamsReplies(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	bob := server.AddNomi("Bob", nomi.MALE, nomi.FRIEND)
+	room := server.AddRoom("Book club", nomi.StatusDefault, alice.UUID, bob.UUID)
+	server.FailNext("RequestNomiRoomMessage", "NoReply")
+
+	session := nomi.NewRoomSession(server.Client(), room, nil)
+	res, replies, err := session.Send(context.Background(), "Hello")
+	if err != nil || res.SentMessage.Text != "Hello" {
+		t.Fatalf("Expected the message to be sent, got %+v, %v", res, err)
+	}
+
+	var got []nomi.RoomReply
+	for reply := range replies {
+		got = append(got, reply)
+	}
+	if len(got) != 2 || got[0].Nomi.Name != "Alice" || got[0].Err == nil || got[1].Message.Text != "Bob heard: Hello" {
+		t.Fatalf("Expected the error of Alice then the reply of Bob, got %+v", got)
+	}
+
+	var asked []nomi.RoomReply
+	for reply := range session.Request(context.Background(), alice) {
+		asked = append(asked, reply)
+	}
+	if len(asked) != 1 || asked[0].Err != nil || asked[0].Nomi.UUID != alice.UUID {
+		t.Fatalf("Expected the reply of Alice, got %+v", asked)
+	}
+}
+
+func TestRoomSessionSerializesRequests(t *testing.T) {
+	room := sessionRoom("Alice", "Bob")
+	var running, overlaps atomic.Int32
+	enter := func() {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+	}
+
+	mock := &nomimock.API{
+		SendRoomMessageContextFunc: func(ctx context.Context, roomID string, body nomi.SendRoomMessageBody) (nomi.SendRoomMessageResponse, error) {
+			enter()
+			return nomi.SendRoomMessageResponse{SentMessage: nomi.Message{Text: body.MessageText}}, nil
+		},
+		RequestNomiRoomMessageContextFunc: func(ctx context.Context, roomID string, body nomi.RequestNomiRoomMessageBody) (nomi.RequestNomiMessageResponse, error) {
+			enter()
+			return nomi.RequestNomiMessageResponse{ReplyMessage: nomi.Message{Text: "reply"}}, nil
+		},
+	}
+	session := nomi.NewRoomSession(mock, room, nomi.AllTurns())
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, replies, err := session.Send(context.Background(), "Hello")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for range replies {
+			}
+		}()
+	}
+	wg.Wait()
+
+	if overlaps.Load() != 0 || mock.CallCount("RequestNomiRoomMessageContext") != 6 {
+		t.Fatalf("Expected 6 requests one at a time, got %d overlaps and %d requests", overlaps.Load(), mock.CallCount("RequestNomiRoomMessageContext"))
+	}
+}
+
+func TestRoomSessionStopsWithContext(t *testing.T) {
+	room := sessionRoom("Alice", "Bob")
+	ctx, cancel := context.WithCancel(context.Background())
+	mock := &nomimock.API{
+		SendRoomMessageContextFunc: func(ctx context.Context, roomID string, body nomi.SendRoomMessageBody) (nomi.SendRoomMessageResponse, error) {
+			return nomi.SendRoomMessageResponse{}, nil
+		},
+		RequestNomiRoomMessageContextFunc: func(ctx context.Context, roomID string, body nomi.RequestNomiRoomMessageBody) (nomi.RequestNomiMessageResponse, error) {
+			cancel()
+			return nomi.RequestNomiMessageResponse{}, nil
+		},
+	}
+	session := nomi.NewRoomSession(mock, room, nil)
+
+	_, replies, _ := session.Send(ctx, "Hello")
+	count := 0
+	for range replies {
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("Expected the replies to stop once ctx is canceled, got %d", count)
+	}
+}
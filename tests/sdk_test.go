@@ -1,35 +1,110 @@
 package tests
 
 import (
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/cassette"
 	"os"
 	"testing"
 )
 
+// cassettePath is where the interactions of the tests below with the real API are recorded
+const cassettePath = "testdata/sdk.json"
+
 var (
 	client     nomi.API
 	testNomiID uuid.UUID
 
 	testRoom nomi.Room
+
+	// live is set when the tests can talk to the Nomi API: for real when NOMI_API_KEY is configured, either in the
+	// environment or in a .env file, or through the recorded cassette otherwise
+	live bool
+	// recorder records the interactions when NOMI_RECORD is set, and replays them when there is no API key
+	recorder *cassette.Transport
+	// setupErr fails the tests against the API when they were asked for but could not be set up
+	setupErr error
+	// skipReason tells why the tests against the API are skipped when live is not set
+	skipReason = "NOMI_API_KEY is not set and there is no cassette to replay, skipping test against the API"
 )
 
+// testNomiMeta is the key under which the cassette stores TEST_NOMI_ID, so that it can be replayed without it
+const testNomiMeta = "TEST_NOMI_ID"
+
 func init() {
-	err := godotenv.Load()
-	if err != nil {
-		panic(err)
+	// the .env file is optional, the variables can also be set in the environment
+	_ = godotenv.Load()
+
+	apiKey := os.Getenv("NOMI_API_KEY")
+	var opts []nomi.Option
+	var err error
+	switch {
+	case apiKey != "" && os.Getenv("NOMI_RECORD") != "":
+		recorder, err = cassette.New(cassettePath, cassette.Record)
+		if err != nil {
+			setupErr = fmt.Errorf("could not record the cassette: %w", err)
+			return
+		}
+	case apiKey == "":
+		recorder, err = cassette.New(cassettePath, cassette.Replay)
+		if err != nil {
+			return
+		}
+	}
+	if recorder != nil {
+		opts = append(opts, nomi.WithHTTPClient(recorder.Client()))
 	}
 
 	testNomiIDRaw := os.Getenv("TEST_NOMI_ID")
+	if testNomiIDRaw == "" && recorder != nil && recorder.Mode() == cassette.Replay {
+		testNomiIDRaw = recorder.Meta(testNomiMeta)
+	}
 	parsed, err := uuid.Parse(testNomiIDRaw)
 	if err != nil {
-		panic("TEST_NOMI_ID is not a valid UUID")
+		// a cassette recorded before the Nomi was stored with it is skipped, a real run without a Nomi fails
+		skipReason = "TEST_NOMI_ID is not a valid UUID and the cassette does not store it, skipping test against the API"
+		if apiKey != "" {
+			setupErr = errors.New("TEST_NOMI_ID is not a valid UUID, set it to the Nomi to test against")
+		}
+		return
+	}
+	if recorder != nil && recorder.Mode() == cassette.Record {
+		recorder.SetMeta(testNomiMeta, parsed.String())
 	}
 
-	client = nomi.NewClient(os.Getenv("NOMI_API_KEY"))
+	client = nomi.NewClient(apiKey, opts...)
 	testNomiID = parsed
+	live = true
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	// a failed run would leave a partial cassette, keep the previous one instead
+	if recorder != nil && setupErr == nil && code == 0 {
+		err := recorder.Save()
+		if err != nil {
+			fmt.Printf("Could not save the cassette. Err: %s\n", err)
+			code = 1
+		}
+	}
+
+	os.Exit(code)
+}
+
+// requireLive skips the tests that talk to the Nomi API when there is neither an API key nor a cassette to replay
+func requireLive(t *testing.T) {
+	t.Helper()
+
+	if setupErr != nil {
+		t.Fatalf("Could not set up the tests against the API. Err: %s", setupErr)
+	}
+	if !live {
+		t.Skip(skipReason)
+	}
 }
 
 func getTestRoom() (room nomi.Room, found bool, err error) {
@@ -48,6 +123,8 @@ func getTestRoom() (room nomi.Room, found bool, err error) {
 }
 
 func TestRoomShouldNotExist(t *testing.T) {
+	requireLive(t)
+
 	_, found, err := getTestRoom()
 	if err != nil {
 		t.Fatalf("Could not get rooms. Err: %s", err)
@@ -59,6 +136,8 @@ func TestRoomShouldNotExist(t *testing.T) {
 }
 
 func TestCreateRoom(t *testing.T) {
+	requireLive(t)
+
 	body := nomi.CreateRoomBody{
 		Name:                  "test-sdk",
 		Note:                  "This is a test room",
@@ -75,6 +154,8 @@ func TestCreateRoom(t *testing.T) {
 }
 
 func TestRoomShouldExist(t *testing.T) {
+	requireLive(t)
+
 	room, found, err := getTestRoom()
 	if err != nil {
 		t.Fatalf("Could not get rooms. Err: %s", err)
@@ -87,6 +168,8 @@ func TestRoomShouldExist(t *testing.T) {
 }
 
 func TestGetTestRoomDetails(t *testing.T) {
+	requireLive(t)
+
 	roomDetails, err := client.GetRoom(testRoom.UUID.String())
 	if err != nil {
 		t.Fatalf("Could not get test room details. Err: %s", err)
@@ -104,6 +187,8 @@ func TestGetTestRoomDetails(t *testing.T) {
 }
 
 func TestSendUserMessageInRoom(t *testing.T) {
+	requireLive(t)
+
 	body := nomi.SendRoomMessageBody{
 		MessageText: "Hi! This is a test message, can you see it?",
 	}
@@ -117,6 +202,8 @@ func TestSendUserMessageInRoom(t *testing.T) {
 }
 
 func TestRequestNomiResponse(t *testing.T) {
+	requireLive(t)
+
 	body := nomi.RequestNomiRoomMessageBody{NomiUUID: testNomiID}
 
 	reply, err := client.RequestNomiRoomMessage(testRoom.UUID.String(), body)
@@ -128,6 +215,8 @@ func TestRequestNomiResponse(t *testing.T) {
 }
 
 func TestSendMultipleUserMessagesInARowInARoom(t *testing.T) {
+	requireLive(t)
+
 	body := nomi.SendRoomMessageBody{
 		MessageText: "Hi! This is another test message, can you still see it?",
 	}
@@ -147,6 +236,8 @@ func TestSendMultipleUserMessagesInARowInARoom(t *testing.T) {
 }
 
 func TestRequestNomiResponseAfterMultipleUserMessages(t *testing.T) {
+	requireLive(t)
+
 	body := nomi.RequestNomiRoomMessageBody{NomiUUID: testNomiID}
 
 	reply, err := client.RequestNomiRoomMessage(testRoom.UUID.String(), body)
@@ -158,6 +249,8 @@ func TestRequestNomiResponseAfterMultipleUserMessages(t *testing.T) {
 }
 
 func TestDeleteRoom(t *testing.T) {
+	requireLive(t)
+
 	success, err := client.DeleteRoom(testRoom.UUID.String())
 	if err != nil {
 		t.Fatalf("Could not delete the test room. Err: %s", err)
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/vhalmd/nomi-go-sdk"
+	"github.com/vhalmd/nomi-go-sdk/nomitest"
+	"testing"
+	"time"
+)
+
+var fastPoll = nomi.WithPollInterval(time.Millisecond, 5*time.Millisecond)
+
+func TestWaitForRoomReady(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	room := server.AddRoom("Lounge", nomi.StatusCreating, alice.UUID)
+	server.FailNext("GetRoom", "RoomStillCreating")
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		server.SetRoomStatus(room.UUID, nomi.StatusDefault)
+	}()
+
+	ready, err := nomi.WaitForRoomReady(context.Background(), server.Client(), room.UUID.String(), fastPoll)
+	if err != nil || ready.Status != nomi.StatusDefault || ready.UUID != room.UUID {
+		t.Fatalf("Expected the Room to become ready, got %+v, %v", ready, err)
+	}
+	if server.Calls("GetRoom") < 3 {
+		t.Fatalf("Expected the Room to be polled, got %d calls", server.Calls("GetRoom"))
+	}
+}
+
+func TestWaitForRoomReadyFailures(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	client := server.Client()
+
+	for status, want := range map[nomi.RoomStatus]error{nomi.StatusError: nomi.RoomFailed, nomi.StatusInitialNoteError: nomi.RoomInitialNoteFailed} {
+		room := server.AddRoom("Broken", status, alice.UUID)
+		_, err := nomi.WaitForRoomReady(context.Background(), client, room.UUID.String(), fastPoll)
+		var statusErr *nomi.RoomStatusError
+		if !errors.Is(err, want) || !errors.As(err, &statusErr) || statusErr.Room.Status != status {
+			t.Fatalf("Expected %v for a Room in %s, got %v", want, status, err)
+		}
+	}
+
+	_, err := nomi.WaitForRoomReady(context.Background(), client, uuid.NewString(), fastPoll)
+	if !errors.Is(err, nomi.RoomNotFound) {
+		t.Fatalf("Expected RoomNotFound, got %v", err)
+	}
+
+	room := server.AddRoom("Slow", nomi.StatusCreating, alice.UUID)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = nomi.WaitForRoomReady(ctx, client, room.UUID.String(), fastPoll)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to be exceeded, got %v", err)
+	}
+}
+
+func TestWatchRoomStatus(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	room := server.AddRoom("Lounge", nomi.StatusCreating, alice.UUID)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := nomi.WatchRoomStatus(ctx, server.Client(), room.UUID.String(), fastPoll)
+	var got []nomi.RoomStatusChange
+	for _, next := range []nomi.RoomStatus{nomi.StatusDefault, nomi.StatusTyping, nomi.StatusDefault, ""} {
+		change := <-changes
+		got = append(got, change)
+		if next == "" {
+			break
+		}
+		server.SetRoomStatus(room.UUID, next)
+	}
+	cancel()
+	for range changes {
+	}
+
+	want := []nomi.RoomStatus{"", nomi.StatusCreating, nomi.StatusDefault, nomi.StatusTyping, nomi.StatusDefault}
+	for i, change := range got {
+		if change.From != want[i] || change.To != want[i+1] || change.Room.UUID != room.UUID {
+			t.Fatalf("Expected the change %d to be from %q to %q, got %+v", i, want[i], want[i+1], change)
+		}
+	}
+}
+
+func TestWaitForNomiReady(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	server.FailNext("GetNomi", "NomiNotReady")
+	server.FailNext("GetNomi", "NomiNotReady")
+
+	n, err := nomi.WaitForNomiReady(context.Background(), server.Client(), alice.UUID.String(), fastPoll)
+	if err != nil || n.UUID != alice.UUID || server.Calls("GetNomi") != 3 {
+		t.Fatalf("Expected Alice after 3 calls, got %+v, %v, %d calls", n, err, server.Calls("GetNomi"))
+	}
+	if server.Calls("SendMessage") != 0 {
+		t.Fatalf("Expected no message to be sent")
+	}
+
+	_, err = nomi.WaitForNomiReady(context.Background(), server.Client(), uuid.NewString(), fastPoll)
+	if !errors.Is(err, nomi.NotFound) {
+		t.Fatalf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestSendMessageWaitsForReady(t *testing.T) {
+	server := nomitest.NewServer()
+	defer server.Close()
+	alice := server.AddNomi("Alice", nomi.FEMALE, nomi.FRIEND)
+	body := nomi.SendMessageBody{MessageText: "Welcome"}
+
+	server.FailNext("SendMessage", "NomiNotReady")
+	if _, err := server.Client().SendMessage(alice.UUID.String(), body); !errors.Is(err, nomi.NotReady) {
+		t.Fatalf("Expected NotReady without waiting, got %v", err)
+	}
+
+	client := server.Client(nomi.WithWaitForReady(time.Second, fastPoll), nomi.WithDailyQuota(10))
+	for range 3 {
+		server.FailNext("SendMessage", "NomiNotReady")
+	}
+	res, err := client.SendMessage(alice.UUID.String(), body)
+	if err != nil || res.ReplyMessage.Text != "Alice heard: Welcome" || server.Calls("SendMessage") != 5 {
+		t.Fatalf("Expected the message to go through after 3 failures, got %+v, %v, %d calls", res, err, server.Calls("SendMessage"))
+	}
+	if stats, _ := nomi.QuotaOf(client); stats.Used != 1 {
+		t.Fatalf("Expected the message to count once, got %+v", stats)
+	}
+
+	server.Fail("SendMessage", "NomiNotReady")
+	client = server.Client(nomi.WithWaitForReady(20*time.Millisecond, fastPoll))
+	if _, err := client.SendMessage(alice.UUID.String(), body); !errors.Is(err, nomi.NotReady) {
+		t.Fatalf("Expected NotReady after the timeout, got %v", err)
+	}
+
+	client = server.Client(nomi.WithWaitForReady(0, fastPoll))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.SendMessageContext(ctx, alice.UUID.String(), body); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to bound the wait, got %v", err)
+	}
+}
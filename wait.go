@@ -0,0 +1,191 @@
+package nomi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RoomFailed is returned by WaitForRoomReady when the Room is in StatusError
+var RoomFailed = errors.New("the room could not be created")
+
+// RoomInitialNoteFailed is returned by WaitForRoomReady when the Room is in StatusInitialNoteError
+var RoomInitialNoteFailed = errors.New("the initial note of the room could not be processed")
+
+// RoomStatusError is returned by WaitForRoomReady when the Room is in a failure status. It matches RoomFailed or
+// RoomInitialNoteFailed with errors.Is
+type RoomStatusError struct {
+	Room Room
+}
+
+func (e *RoomStatusError) Error() string {
+	return fmt.Sprintf("room %s is in status %s: %s", e.Room.UUID, e.Room.Status, e.Unwrap())
+}
+
+func (e *RoomStatusError) Unwrap() error {
+	if e.Room.Status == StatusInitialNoteError {
+		return RoomInitialNoteFailed
+	}
+
+	return RoomFailed
+}
+
+// WaitOption changes how often the waiters poll the API
+type WaitOption func(*waitConfig)
+
+type waitConfig struct {
+	poll RetryPolicy
+}
+
+// WithPollInterval makes the waiters poll every initial at first, backing off up to maxInterval
+func WithPollInterval(initial time.Duration, maxInterval time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.poll.InitialBackoff = initial
+		c.poll.MaxBackoff = maxInterval
+	}
+}
+
+func newWaitConfig(opts []WaitOption) waitConfig {
+	c := waitConfig{poll: RetryPolicy{
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     1.5,
+		Jitter:         0.1,
+	}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// IsRoomReady reports whether messages can be sent in a Room with status
+func IsRoomReady(status RoomStatus) bool {
+	switch status {
+	case StatusDefault, StatusWaiting, StatusTyping, StatusManual:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForRoomReady polls GetRoom with backoff until the Room can take messages and returns it. It returns a
+// *RoomStatusError if the Room is in StatusError or StatusInitialNoteError, the error of GetRoom if it is not
+// transient, and the error of ctx when it is done
+func WaitForRoomReady(ctx context.Context, client API, roomID string, opts ...WaitOption) (Room, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var last Room
+	for change := range WatchRoomStatus(ctx, client, roomID, opts...) {
+		if change.Err != nil {
+			return last, change.Err
+		}
+
+		last = change.Room
+		switch {
+		case IsRoomReady(change.To):
+			return change.Room, nil
+		case change.To == StatusError, change.To == StatusInitialNoteError:
+			return change.Room, &RoomStatusError{Room: change.Room}
+		}
+	}
+
+	return last, contextError(ctx, ctx.Err())
+}
+
+// RoomStatusChange is a transition of the status of a Room seen by WatchRoomStatus
+type RoomStatusChange struct {
+	// From is empty for the first status seen
+	From RoomStatus
+	To   RoomStatus
+	Room Room
+	// Err is the error that stopped the watch, on the last change sent
+	Err error
+}
+
+// WatchRoomStatus polls GetRoom until ctx is done and sends a change on the returned channel each time the status of
+// the Room differs from the previous one, starting with the current status. Polling backs off while the status stays
+// the same and starts over after each change. Transient errors are ignored; other errors are sent as a last change
+// before the channel is closed
+func WatchRoomStatus(ctx context.Context, client API, roomID string, opts ...WaitOption) <-chan RoomStatusChange {
+	config := newWaitConfig(opts)
+	changes := make(chan RoomStatusChange)
+
+	go func() {
+		defer close(changes)
+
+		var status RoomStatus
+		polls := 0
+		for {
+			res, err := client.GetRoomContext(ctx, roomID)
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil && !IsTransient(err):
+				select {
+				case changes <- RoomStatusChange{From: status, Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			case err == nil && res.Status != status:
+				select {
+				case changes <- RoomStatusChange{From: status, To: res.Status, Room: Room(res)}:
+				case <-ctx.Done():
+					return
+				}
+				status = res.Status
+				polls = 0
+			}
+
+			polls++
+			if sleep(ctx, config.poll.backoff(polls, err)) != nil {
+				return
+			}
+		}
+	}()
+
+	return changes
+}
+
+// WaitForNomiReady polls GetNomi with backoff until the Nomi is returned and returns it, without sending any message.
+// It returns the error of GetNomi if it is not transient, and the error of ctx when it is done. The API has no
+// readiness signal: a Nomi is only known to be ready once it accepts a message, so the first messages to a Nomi
+// created a moment ago may still fail with NotReady after WaitForNomiReady returns. Use WithWaitForReady to have
+// SendMessage wait through them
+func WaitForNomiReady(ctx context.Context, client API, nomiID string, opts ...WaitOption) (Nomi, error) {
+	config := newWaitConfig(opts)
+
+	for poll := 1; ; poll++ {
+		res, err := client.GetNomiContext(ctx, nomiID)
+		switch {
+		case err == nil:
+			return Nomi(res), nil
+		case ctx.Err() != nil:
+			return Nomi{}, contextError(ctx, ctx.Err())
+		case !IsTransient(err):
+			return Nomi{}, err
+		}
+
+		if err := sleep(ctx, config.poll.backoff(poll, err)); err != nil {
+			return Nomi{}, err
+		}
+	}
+}
+
+// waitReady is how SendMessage waits for a Nomi that is not ready
+type waitReady struct {
+	poll    RetryPolicy
+	timeout time.Duration
+}
+
+// WithWaitForReady makes SendMessage wait while the Nomi is not ready, right after its creation, sending it again with
+// backoff until the message goes through, ctx is done or timeout has elapsed. The API only tells a Nomi is not ready
+// by refusing messages, so this is the only way to wait until it accepts them. A zero timeout waits as long as ctx allows. NotReady is
+// returned once the wait is over. WaitOptions change how often the message is sent again
+func WithWaitForReady(timeout time.Duration, opts ...WaitOption) Option {
+	return func(a *api) {
+		a.waitReady = &waitReady{poll: newWaitConfig(opts).poll, timeout: timeout}
+	}
+}